@@ -0,0 +1,113 @@
+package framework
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+)
+
+// defaultPendingRetryDelay is how long a tenant waits before trying to start
+// again when all concurrent start slots are taken.
+const defaultPendingRetryDelay = time.Second
+
+// startAdmission limits how many tenants start their controllers at the same
+// time and how fast, so that a cold start of many tenants does not flood the
+// API server. The zero limits admit every start.
+type startAdmission struct {
+	// slots holds one element per start in progress. It is nil when the
+	// number of concurrent starts is not limited.
+	slots chan struct{}
+	// limiter is the token bucket every start takes a token from. It is nil
+	// when the start rate is not limited.
+	limiter *rate.Limiter
+	// jitter is the maximum random delay added to the wait of pending tenants
+	// so that they do not retry in lockstep.
+	jitter time.Duration
+	clock  clock.PassiveClock
+
+	mu sync.Mutex
+	// pending holds the keys of the tenants whose last start was not admitted.
+	pending sets.Set[string]
+}
+
+func newStartAdmission(maxConcurrentStarts int, qps float64, burst int, jitter time.Duration, clk clock.PassiveClock) *startAdmission {
+	a := &startAdmission{jitter: jitter, clock: clk, pending: sets.New[string]()}
+	if maxConcurrentStarts > 0 {
+		a.slots = make(chan struct{}, maxConcurrentStarts)
+	}
+	if qps > 0 {
+		a.limiter = rate.NewLimiter(rate.Limit(qps), burst)
+	}
+	return a
+}
+
+// admit reserves a start of the tenant with the given key. If the start is
+// admitted, release must be called once the start no longer needs its slot;
+// it may be called more than once. Otherwise, the tenant is pending and wait is
+// how long it should stay pending before trying again.
+func (a *startAdmission) admit(key string) (release func(), wait time.Duration, admitted bool) {
+	release, wait, admitted = a.reserve()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if admitted {
+		a.pending.Delete(key)
+	} else {
+		a.pending.Insert(key)
+	}
+	return release, wait, admitted
+}
+
+func (a *startAdmission) reserve() (release func(), wait time.Duration, admitted bool) {
+	if a.slots != nil {
+		select {
+		case a.slots <- struct{}{}:
+		default:
+			return nil, a.withJitter(defaultPendingRetryDelay), false
+		}
+	}
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			if a.slots != nil {
+				<-a.slots
+			}
+		})
+	}
+	if a.limiter != nil {
+		now := a.clock.Now()
+		r := a.limiter.ReserveN(now, 1)
+		if delay := r.DelayFrom(now); delay > 0 {
+			r.CancelAt(now)
+			release()
+			return nil, a.withJitter(delay), false
+		}
+	}
+	return release, 0, true
+}
+
+// forget stops reporting the tenant with the given key as pending, e.g. once
+// it is stopped or deleted.
+func (a *startAdmission) forget(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending.Delete(key)
+}
+
+// pendingKeys returns the keys of the tenants whose start is pending.
+func (a *startAdmission) pendingKeys() sets.Set[string] {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.pending.Clone()
+}
+
+// withJitter adds a random delay of up to the configured jitter to d.
+func (a *startAdmission) withJitter(d time.Duration) time.Duration {
+	if a.jitter <= 0 {
+		return d
+	}
+	return d + time.Duration(rand.Int63n(int64(a.jitter)))
+}
@@ -0,0 +1,234 @@
+package framework
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/fake"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// TestStartAdmissionLimitsConcurrentStarts verifies that no more than the
+// configured number of starts are admitted until one is released.
+func TestStartAdmissionLimitsConcurrentStarts(t *testing.T) {
+	a := newStartAdmission(2, 0, 0, 0, testingclock.NewFakeClock(time.Now()))
+
+	release1, _, ok1 := a.admit("pc")
+	_, _, ok2 := a.admit("pc")
+	if !ok1 || !ok2 {
+		t.Fatalf("Expected the first two starts to be admitted")
+	}
+	_, wait, ok := a.admit("pc")
+	if ok {
+		t.Fatalf("Expected the third concurrent start to be pending")
+	}
+	if wait != defaultPendingRetryDelay {
+		t.Errorf("Expected pending start to wait %v, got %v", defaultPendingRetryDelay, wait)
+	}
+
+	release1()
+	if _, _, ok := a.admit("pc"); !ok {
+		t.Errorf("Expected a start to be admitted after a release")
+	}
+}
+
+// TestStartAdmissionRateLimit verifies that starts take tokens from the bucket
+// and that a pending start waits for the next token, plus at most the jitter.
+func TestStartAdmissionRateLimit(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	jitter := 100 * time.Millisecond
+	a := newStartAdmission(0, 1, 2, jitter, fakeClock)
+
+	for i := 0; i < 2; i++ {
+		release, _, ok := a.admit("pc")
+		if !ok {
+			t.Fatalf("Expected start %d to be admitted within the burst", i)
+		}
+		release()
+	}
+	_, wait, ok := a.admit("pc")
+	if ok {
+		t.Fatalf("Expected start to be pending once the burst is used up")
+	}
+	if wait < time.Second || wait >= time.Second+jitter {
+		t.Errorf("Expected pending start to wait between 1s and %v, got %v", time.Second+jitter, wait)
+	}
+
+	fakeClock.Step(time.Second)
+	if _, _, ok := a.admit("pc"); !ok {
+		t.Errorf("Expected start to be admitted once a token is available")
+	}
+}
+
+// TestManagerStartPendingUntilAdmitted verifies that a tenant over the start
+// rate limit is reported as pending, requeued, and started once admitted.
+func TestManagerStartPendingUntilAdmitted(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newMockControllerStarter()
+	recorder := &requeueRecorder{}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+
+	manager := newManager(
+		dynamicClient,
+		"test-finalizer",
+		starter,
+		WithStartRateLimit(1, 1),
+		WithClock(fakeClock),
+	)
+	manager.requeueAfter = recorder.requeueAfter
+
+	for _, name := range []string{"pc-first", "pc-second"} {
+		pc := createTestProviderConfig(name)
+		if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+			t.Fatalf("Failed to create ProviderConfig %s: %v", name, err)
+		}
+		if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+			t.Fatalf("Start of %s failed: %v", name, err)
+		}
+	}
+
+	if got := starter.getStartCallCount(); got != 1 {
+		t.Fatalf("Expected 1 start call, got %d", got)
+	}
+	if _, exists := manager.controllers.Get("pc-second"); exists {
+		t.Errorf("Did not expect a controller map entry for the pending ProviderConfig")
+	}
+	if cond := conditionFromClient(ctx, t, dynamicClient, "pc-second", ConditionControllersRunning); cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != ReasonStartPending {
+		t.Errorf("Expected ControllersRunning condition to be False with reason %s, got %+v", ReasonStartPending, cond)
+	}
+	if got := recorder.get(); len(got) != 1 || got[0] != time.Second {
+		t.Errorf("Expected pending ProviderConfig to be requeued after 1s, got %v", got)
+	}
+
+	fakeClock.Step(time.Second)
+	pc, err := providerConfigFromClient(ctx, dynamicClient, "pc-second")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start of pc-second failed: %v", err)
+	}
+	if got := starter.getStartCallCount(); got != 2 {
+		t.Errorf("Expected 2 start calls, got %d", got)
+	}
+	if cond := conditionFromClient(ctx, t, dynamicClient, "pc-second", ConditionControllersRunning); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("Expected ControllersRunning condition to be True once admitted, got %+v", cond)
+	}
+}
+
+// TestManagerHoldsStartSlotUntilReady verifies that a start keeps its slot
+// until its controllers are ready, so that the next tenant stays pending.
+func TestManagerHoldsStartSlotUntilReady(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := &readyChannelStarter{mockControllerStarter: newMockControllerStarter(), ready: make(chan struct{})}
+	manager := newManager(dynamicClient, "test-finalizer", starter, WithMaxConcurrentStarts(1))
+	manager.requeueAfter = (&requeueRecorder{}).requeueAfter
+
+	start := func(name string) {
+		t.Helper()
+		pc, err := providerConfigFromClient(ctx, dynamicClient, name)
+		if err != nil {
+			t.Fatalf("Failed to get ProviderConfig %s: %v", name, err)
+		}
+		if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+			t.Fatalf("Start of %s failed: %v", name, err)
+		}
+	}
+	for _, name := range []string{"pc-first", "pc-second"} {
+		if err := createProviderConfigInClient(ctx, dynamicClient, createTestProviderConfig(name)); err != nil {
+			t.Fatalf("Failed to create ProviderConfig %s: %v", name, err)
+		}
+		start(name)
+	}
+	if got := starter.getStartCallCount(); got != 1 {
+		t.Fatalf("Expected 1 start call while pc-first is not ready, got %d", got)
+	}
+	if !manager.PendingProviderConfigs().Has("pc-second") {
+		t.Error("Expected pc-second to be pending")
+	}
+
+	close(starter.ready)
+	if err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		start("pc-second")
+		return starter.getStartCallCount() == 2, nil
+	}); err != nil {
+		t.Fatalf("Expected pc-second to start once pc-first is ready: %v", err)
+	}
+	if manager.PendingProviderConfigs().Has("pc-second") {
+		t.Error("Did not expect pc-second to be pending once started")
+	}
+}
+
+// TestManagerReleasesStartSlotOnReadinessTimeout verifies that a start whose
+// controllers never become ready gives up its slot after the readiness timeout,
+// or after the default readiness timeout if readiness is waited for forever.
+func TestManagerReleasesStartSlotOnReadinessTimeout(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		readinessTimeout time.Duration
+		wantRelease      time.Duration
+	}{
+		{
+			desc:             "readiness timeout",
+			readinessTimeout: time.Minute,
+			wantRelease:      time.Minute,
+		},
+		{
+			desc:             "no readiness timeout",
+			readinessTimeout: 0,
+			wantRelease:      defaultReadinessTimeout,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := context.Background()
+			dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+			starter := &readyChannelStarter{mockControllerStarter: newMockControllerStarter(), ready: make(chan struct{})}
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			manager := newManager(dynamicClient, "test-finalizer", starter,
+				WithMaxConcurrentStarts(1),
+				WithReadinessTimeout(tc.readinessTimeout),
+				WithClock(fakeClock),
+			)
+			manager.requeueAfter = (&requeueRecorder{}).requeueAfter
+
+			pc := createTestProviderConfig("pc-slow")
+			if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+				t.Fatalf("Failed to create ProviderConfig: %v", err)
+			}
+			if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			if _, _, admitted := manager.admission.admit("pc-other"); admitted {
+				t.Fatal("Expected the slot to be held while pc-slow is not ready")
+			}
+
+			if err := wait.PollUntilContextTimeout(ctx, time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+				return fakeClock.HasWaiters(), nil
+			}); err != nil {
+				t.Fatalf("Expected the slot timeout to be armed: %v", err)
+			}
+			fakeClock.Step(tc.wantRelease - time.Second)
+			time.Sleep(50 * time.Millisecond)
+			if _, _, admitted := manager.admission.admit("pc-other"); admitted {
+				t.Fatalf("Expected the slot to be held for %v", tc.wantRelease)
+			}
+			fakeClock.Step(time.Second)
+			if err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+				release, _, admitted := manager.admission.admit("pc-other")
+				if admitted {
+					release()
+				}
+				return admitted, nil
+			}); err != nil {
+				t.Fatalf("Expected the slot to be released after %v: %v", tc.wantRelease, err)
+			}
+		})
+	}
+}
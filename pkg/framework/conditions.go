@@ -0,0 +1,143 @@
+package framework
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Condition types written by the framework to the status of a ProviderConfig.
+const (
+	// ConditionControllersRunning is True while the controllers for the
+	// ProviderConfig are running.
+	ConditionControllersRunning = "ControllersRunning"
+	// ConditionStartFailed is True when the most recent attempt to start the
+	// controllers for the ProviderConfig failed.
+	ConditionStartFailed = "StartFailed"
+	// ConditionTerminating is True once the ProviderConfig is being deleted and
+	// its controllers are being stopped.
+	ConditionTerminating = "Terminating"
+	// ConditionPaused is True while the controllers for the ProviderConfig are
+	// stopped because of PausedAnnotation.
+	ConditionPaused = "Paused"
+	// ConditionControllersReady is True once all running controllers for the
+	// ProviderConfig are ready to serve, see ReadinessReporter.
+	ConditionControllersReady = "ControllersReady"
+	// ConditionDegraded is True after a goroutine of the controllers for the
+	// ProviderConfig panicked, until the restarted controllers are ready.
+	ConditionDegraded = "Degraded"
+)
+
+// Condition reasons written by the framework to the status of a ProviderConfig.
+const (
+	// ReasonControllersStarted indicates that the controllers were started successfully.
+	ReasonControllersStarted = "ControllersStarted"
+	// ReasonStartPending indicates that the controllers are waiting for their
+	// turn to start because of the start admission limits.
+	ReasonStartPending = "StartPending"
+	// ReasonControllersUpdated indicates that a spec change was applied to the
+	// running controllers without restarting them.
+	ReasonControllersUpdated = "ControllersUpdated"
+	// ReasonFinalizerUpdateFailed indicates that the finalizer could not be added,
+	// so the controllers were not started.
+	ReasonFinalizerUpdateFailed = "FinalizerUpdateFailed"
+	// ReasonControllerStartFailed indicates that the ControllerStarter returned an error.
+	ReasonControllerStartFailed = "ControllerStartFailed"
+	// ReasonControllersStopped indicates that the controllers were stopped.
+	ReasonControllersStopped = "ControllersStopped"
+	// ReasonControllersStopping indicates that the controllers were asked to
+	// stop and the finalizer is kept until they exit or the stop timeout expires.
+	ReasonControllersStopping = "ControllersStopping"
+	// ReasonControllersExited indicates that the controllers exited without being
+	// asked to stop and are waiting to be restarted.
+	ReasonControllersExited = "ControllersExited"
+	// ReasonStopTimedOut indicates that the controllers did not exit within the
+	// stop timeout and the finalizer was removed regardless.
+	ReasonStopTimedOut = "StopTimedOut"
+	// ReasonDeletionRequested indicates that the ProviderConfig has a deletion timestamp.
+	ReasonDeletionRequested = "DeletionRequested"
+	// ReasonPauseRequested indicates that the ProviderConfig is annotated with
+	// PausedAnnotation, so its controllers were stopped.
+	ReasonPauseRequested = "PauseRequested"
+	// ReasonResumed indicates that PausedAnnotation was removed and the
+	// controllers were started again.
+	ReasonResumed = "Resumed"
+	// ReasonControllersReady indicates that all running controllers are ready.
+	ReasonControllersReady = "ControllersReady"
+	// ReasonControllersStarting indicates that some controllers were started
+	// but are not ready yet.
+	ReasonControllersStarting = "ControllersStarting"
+	// ReasonReadinessTimedOut indicates that some controllers did not become
+	// ready within the readiness timeout and are being restarted.
+	ReasonReadinessTimedOut = "ReadinessTimedOut"
+	// ReasonControllersPanicked indicates that a goroutine of the controllers
+	// panicked, so they are being restarted.
+	ReasonControllersPanicked = "ControllersPanicked"
+	// ReasonControllersRecovered indicates that the controllers restarted after
+	// a panic are ready.
+	ReasonControllersRecovered = "ControllersRecovered"
+)
+
+// conditionsFromUnstructured returns the status conditions stored in the object.
+func conditionsFromUnstructured(obj *unstructured.Unstructured) ([]metav1.Condition, error) {
+	raw, found, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status conditions: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	conditions := make([]metav1.Condition, 0, len(raw))
+	for _, r := range raw {
+		m, ok := r.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected status condition to be an object, but got %T", r)
+		}
+		var c metav1.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &c); err != nil {
+			return nil, fmt.Errorf("failed to convert status condition: %w", err)
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+// setConditionsInUnstructured replaces the status conditions stored in the object.
+func setConditionsInUnstructured(obj *unstructured.Unstructured, conditions []metav1.Condition) error {
+	raw := make([]any, 0, len(conditions))
+	for i := range conditions {
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&conditions[i])
+		if err != nil {
+			return fmt.Errorf("failed to convert status condition %s: %w", conditions[i].Type, err)
+		}
+		raw = append(raw, m)
+	}
+	return unstructured.SetNestedSlice(obj.Object, raw, "status", "conditions")
+}
+
+// mergeConditions applies the given conditions to the object's status and
+// reports whether anything changed. Every condition is stamped with
+// observedGeneration.
+func mergeConditions(obj *unstructured.Unstructured, observedGeneration int64, conditions ...metav1.Condition) (bool, error) {
+	existing, err := conditionsFromUnstructured(obj)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	for _, c := range conditions {
+		c.ObservedGeneration = observedGeneration
+		if meta.SetStatusCondition(&existing, c) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	if err := setConditionsInUnstructured(obj, existing); err != nil {
+		return false, err
+	}
+	return true, nil
+}
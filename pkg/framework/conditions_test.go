@@ -0,0 +1,59 @@
+package framework
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestMergeConditions verifies that conditions round-trip through the unstructured
+// status and that unchanged conditions are not reported as changes.
+func TestMergeConditions(t *testing.T) {
+	pc := createTestProviderConfig("test-pc")
+
+	changed, err := mergeConditions(pc, 3, startFailedConditions(ReasonControllerStartFailed, errors.New("boom"))...)
+	if err != nil {
+		t.Fatalf("mergeConditions() failed: %v", err)
+	}
+	if !changed {
+		t.Fatal("Expected first merge to report a change")
+	}
+
+	conditions, err := conditionsFromUnstructured(pc)
+	if err != nil {
+		t.Fatalf("conditionsFromUnstructured() failed: %v", err)
+	}
+	startFailed := meta.FindStatusCondition(conditions, ConditionStartFailed)
+	if startFailed == nil {
+		t.Fatalf("Expected %s condition, got %v", ConditionStartFailed, conditions)
+	}
+	if startFailed.Status != metav1.ConditionTrue || startFailed.Message != "boom" || startFailed.ObservedGeneration != 3 {
+		t.Errorf("Unexpected %s condition: %+v", ConditionStartFailed, startFailed)
+	}
+	if startFailed.LastTransitionTime.IsZero() {
+		t.Errorf("Expected LastTransitionTime to be set on %s", ConditionStartFailed)
+	}
+
+	changed, err = mergeConditions(pc, 3, startFailedConditions(ReasonControllerStartFailed, errors.New("boom"))...)
+	if err != nil {
+		t.Fatalf("mergeConditions() failed: %v", err)
+	}
+	if changed {
+		t.Error("Expected identical merge to report no change")
+	}
+}
+
+// TestConditionsFromUnstructuredRejectsMalformedStatus verifies that malformed
+// conditions produce an error instead of being silently dropped.
+func TestConditionsFromUnstructuredRejectsMalformedStatus(t *testing.T) {
+	pc := createTestProviderConfig("test-pc")
+	pc.Object["status"] = map[string]any{
+		"conditions": []any{"not-a-condition"},
+	}
+
+	if _, err := conditionsFromUnstructured(pc); err == nil {
+		t.Fatal("Expected error for malformed status conditions")
+	}
+}
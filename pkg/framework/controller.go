@@ -10,12 +10,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"math/rand"
 	"runtime/debug"
+	"sync"
+	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 
 	"k8s.io/klog/v2"
 	mtcontext "github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework/mtcontext"
@@ -35,16 +41,117 @@ type ControllerStarter interface {
 	StartController(pc *unstructured.Unstructured) (chan<- struct{}, error)
 }
 
+// DefaultControllerStarterName is the name under which the ControllerStarter
+// passed to New is registered.
+const DefaultControllerStarterName = "default"
+
+// ControllerHandle describes the controllers started for a ProviderConfig.
+type ControllerHandle struct {
+	// StopCh is closed by the framework to signal the controllers to stop.
+	StopCh chan<- struct{}
+	// Done is closed by the starter once all of the controllers have exited.
+	// If Done is nil, the stop is considered complete as soon as StopCh is closed.
+	// Closing Done before StopCh is closed reports that the controllers exited
+	// unexpectedly; the framework then restarts them with exponential backoff.
+	Done <-chan struct{}
+	// Ready is closed by the starter once the controllers are ready to serve,
+	// e.g. once their informer caches have synced. If Ready is nil, the
+	// controllers are ready as soon as they are started, unless the starter
+	// implements ReadinessReporter.
+	Ready <-chan struct{}
+}
+
+// HandleControllerStarter is an optional interface that a ControllerStarter can
+// implement to report when its controllers have exited. If the starter passed
+// to New implements it, the framework calls StartControllerWithHandle instead of
+// StartController and keeps the ProviderConfig finalizer until Done is closed
+// or the stop timeout (see WithStopTimeout) expires.
+type HandleControllerStarter interface {
+	// StartControllerWithHandle starts controller(s) for the given ProviderConfig
+	// and returns a handle used to stop them and to wait for them to exit.
+	StartControllerWithHandle(pc *unstructured.Unstructured) (*ControllerHandle, error)
+}
+
+// ContextControllerStarter is an optional interface that a ControllerStarter can
+// implement to receive a context when its controllers are started. If a starter
+// implements it, the framework calls StartControllerWithContext instead of
+// StartController or StartControllerWithHandle. Starters that only implement
+// ContextControllerStarter can be registered through AdaptContextControllerStarter.
+type ContextControllerStarter interface {
+	// StartControllerWithContext starts controller(s) for the given ProviderConfig.
+	// ctx carries the tenant UID (see mtcontext) and a logger scoped to the
+	// tenant and starter (see klog.FromContext). The framework cancels ctx when
+	// the controllers must stop; they should then exit and close the returned
+	// done channel. A nil done channel means the stop is complete as soon as
+	// ctx is cancelled. Closing done before ctx is cancelled reports that the
+	// controllers exited unexpectedly. Goroutines started with Go(ctx, ...)
+	// have their panics recovered and the controllers restarted, instead of
+	// crashing the process.
+	StartControllerWithContext(ctx context.Context, pc *unstructured.Unstructured) (done <-chan struct{}, err error)
+}
+
+// ControllerUpdater is an optional interface that a ControllerStarter can
+// implement to apply ProviderConfig spec changes to running controllers without
+// restarting them. It is only used with SpecChangePolicyUpdate.
+type ControllerUpdater interface {
+	// UpdateController applies the spec of the given ProviderConfig to the
+	// controllers previously started for it. If it returns an error, the
+	// update is retried with backoff.
+	UpdateController(pc *unstructured.Unstructured) error
+}
+
+// ReadinessReporter is an optional interface that a ControllerStarter can
+// implement to report when the controllers it started for a ProviderConfig are
+// ready to serve. Until they are, the tenant is reported as starting. The
+// framework polls HasSynced after every start and restarts controllers that
+// are not ready within the readiness timeout (see WithReadinessTimeout).
+type ReadinessReporter interface {
+	// HasSynced reports whether the controllers started for the given
+	// ProviderConfig are ready, e.g. whether their informer caches have synced.
+	HasSynced(pc *unstructured.Unstructured) bool
+}
+
 const (
 	providerConfigControllerName = "provider-config-controller"
 	resourceName                 = "provider-configs"
-	workersCount                 = 5
+	// workersCount is the default number of workers, see WithWorkers.
+	workersCount = 5
 )
 
 // controllerManager implements the logic for starting and stopping controllers for each ProviderConfig.
 type controllerManager interface {
 	StartControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) error
 	StopControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) error
+	// StopAllControllers stops the controllers of every ProviderConfig in
+	// parallel without removing finalizers, e.g. when this replica is no longer
+	// the leader or shuts down. It reports the ProviderConfigs whose controllers
+	// did not exit before ctx is done.
+	StopAllControllers(ctx context.Context) error
+	// ReleaseControllersForProviderConfig stops the controllers of a single
+	// ProviderConfig without removing its finalizer, e.g. when another replica
+	// takes it over. It reports whether the controllers have exited; if not,
+	// the ProviderConfig is requeued to check again.
+	ReleaseControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) bool
+	// CleanupControllersForProviderConfig tears down the controllers of a
+	// ProviderConfig that was deleted without going through its finalizer.
+	CleanupControllersForProviderConfig(ctx context.Context, key string) error
+	// PauseControllersForProviderConfig stops the controllers of a ProviderConfig
+	// annotated with PausedAnnotation without removing its finalizer.
+	PauseControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) error
+	// TrackedProviderConfigs returns the keys of the ProviderConfigs that have
+	// controllers, mapped to whether any of their controllers are running.
+	TrackedProviderConfigs() map[string]bool
+	// PendingProviderConfigs returns the keys of the ProviderConfigs whose
+	// start is pending admission.
+	PendingProviderConfigs() sets.Set[string]
+	// TenantUID returns the tenant UID recorded when the controllers of the
+	// ProviderConfig with the given key were started, or "" if none is known.
+	TenantUID(key string) string
+	// DebugTenants describes the controllers tracked for every ProviderConfig,
+	// keyed by ProviderConfig key.
+	DebugTenants() map[string]TenantDebugInfo
+	// HasFinalizer reports whether pc carries the framework finalizer.
+	HasFinalizer(pc *unstructured.Unstructured) bool
 }
 
 // Controller manages the ProviderConfig resource lifecycle.
@@ -54,44 +161,128 @@ type Controller struct {
 	manager controllerManager
 
 	providerConfigLister cache.Indexer
-	providerConfigQueue  taskqueue.TaskQueue
+	providerConfigQueue  taskqueue.PriorityTaskQueue
 	workersCount         int
 	stopCh               <-chan struct{}
 	hasSynced            func() bool
+	// tenants describes the resource whose objects define tenants.
+	tenants tenantResource
+	// leaderElection is non-nil when workers only run while holding a Lease.
+	leaderElection *LeaderElectionConfig
+	// sharder is non-nil when ProviderConfigs are sharded across replicas.
+	sharder *sharder
+	// configErr is the reason the Controller refuses to run, i.e. invalid
+	// options. Running with the defaults instead, or without the requested
+	// sharding, would not behave as configured.
+	configErr error
+	// driftReconcileInterval is how often reconcileDrift runs; zero disables it.
+	driftReconcileInterval time.Duration
+	// shutdownTimeout bounds how long stopAllTenants waits for the controllers
+	// of all tenants to exit.
+	shutdownTimeout time.Duration
+	// clock drives the drift reconciler.
+	clock   clock.WithTicker
+	metrics *metrics
+
+	// pausedMu guards paused, the keys of the ProviderConfigs whose controllers
+	// are paused through PausedAnnotation.
+	pausedMu sync.Mutex
+	paused   map[string]bool
+
+	// syncErrorsMu guards syncErrors, the errors of the last failed sync of
+	// each key, reported by DebugHandler.
+	syncErrorsMu sync.Mutex
+	syncErrors   map[string]syncError
+
+	// eventBroadcaster delivers the Events of the manager. It is nil unless
+	// Events are enabled.
+	eventBroadcaster record.EventBroadcaster
 }
 
-// New creates a new Controller that manages ProviderConfig resources.
-func New(client dynamic.Interface, providerConfigInformer cache.SharedIndexInformer, finalizerName string, controllerStarter ControllerStarter, stopCh <-chan struct{},
+// New creates a new Controller that manages ProviderConfig resources. If opts
+// are invalid, the error is logged and Run returns right away; use NewValidated
+// to get the error instead.
+func New(client dynamic.Interface, providerConfigInformer cache.SharedIndexInformer, finalizerName string, controllerStarter ControllerStarter, stopCh <-chan struct{}, opts ...Option,
 ) *Controller {
 	manager := newManager(
 		client,
 		finalizerName,
 		controllerStarter,
+		opts...,
 	)
-	return newController(manager, providerConfigInformer, stopCh)
+	c := newController(manager, providerConfigInformer, stopCh, opts...)
+	o := newOptions(opts...)
+	if o.sharding != nil && c.configErr == nil {
+		if s, err := newSharder(*o.sharding, client, o.tenants, o.clock); err != nil {
+			c.configErr = fmt.Errorf("invalid sharding configuration: %w", err)
+		} else {
+			c.sharder = s
+		}
+	}
+	if o.events != nil {
+		manager.recorder, c.eventBroadcaster = newEventRecorder(*o.events)
+	}
+	manager.requeueAfter = func(key string, delay time.Duration) {
+		c.providerConfigQueue.EnqueueAfter(cache.ExplicitKey(key), delay)
+	}
+	return c
+}
+
+// NewValidated is like New, but returns an error instead of a Controller that
+// refuses to run if opts are invalid.
+func NewValidated(client dynamic.Interface, providerConfigInformer cache.SharedIndexInformer, finalizerName string, controllerStarter ControllerStarter, stopCh <-chan struct{}, opts ...Option,
+) (*Controller, error) {
+	if err := newOptions(opts...).validate(); err != nil {
+		return nil, fmt.Errorf("invalid ProviderConfig Controller options: %w", err)
+	}
+	c := New(client, providerConfigInformer, finalizerName, controllerStarter, stopCh, opts...)
+	if c.configErr != nil {
+		c.providerConfigQueue.Shutdown()
+		return nil, c.configErr
+	}
+	return c, nil
 }
 
 // newController creates a Controller with the given manager. Used for testing.
-func newController(manager controllerManager, providerConfigInformer cache.SharedIndexInformer, stopCh <-chan struct{}) *Controller {
+func newController(manager controllerManager, providerConfigInformer cache.SharedIndexInformer, stopCh <-chan struct{}, opts ...Option) *Controller {
+	o := newOptions(opts...)
 	c := &Controller{
-		providerConfigLister: providerConfigInformer.GetIndexer(),
-		stopCh:               stopCh,
-		workersCount:         workersCount,
-		hasSynced:            providerConfigInformer.HasSynced,
-		manager:              manager,
+		providerConfigLister:   providerConfigInformer.GetIndexer(),
+		stopCh:                 stopCh,
+		workersCount:           o.workers,
+		tenants:                o.tenants,
+		hasSynced:              providerConfigInformer.HasSynced,
+		manager:                manager,
+		leaderElection:         o.leaderElection,
+		driftReconcileInterval: o.driftReconcileInterval,
+		shutdownTimeout:        o.shutdownTimeout,
+		clock:                  o.clock,
+		metrics:                newMetrics(o.metricFactory),
+		paused:                 map[string]bool{},
+		syncErrors:             map[string]syncError{},
+	}
+	if err := o.validate(); err != nil {
+		c.configErr = fmt.Errorf("invalid ProviderConfig Controller options: %w", err)
 	}
 
-	c.providerConfigQueue = taskqueue.NewPeriodicTaskQueueWithMultipleWorkers(providerConfigControllerName, resourceName, c.workersCount, c.syncWrapper)
+	queueOptions := append([]taskqueue.Option{taskqueue.WithClock(o.clock), taskqueue.WithPriorities(), taskqueue.WithKeyFunc(o.tenants.queueKey)}, o.queueOptions...)
+	c.providerConfigQueue = taskqueue.NewPeriodicTaskQueueWithMultipleWorkers(o.queueName, resourceName, c.workersCount, c.syncWrapper, queueOptions...)
 
 	providerConfigInformer.AddEventHandler(
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj any) {
 				klog.V(4).InfoS("Enqueue add event", "object", obj)
-				c.providerConfigQueue.Enqueue(obj)
+				c.providerConfigQueue.EnqueueWithPriority(obj, enqueuePriority(obj, taskqueue.PriorityNew))
 			},
 			UpdateFunc: func(old, cur any) {
 				klog.V(4).InfoS("Enqueue update event", "old", old, "new", cur)
-				c.providerConfigQueue.Enqueue(cur)
+				c.providerConfigQueue.EnqueueWithPriority(cur, updatePriority(old, cur))
+			},
+			DeleteFunc: func(obj any) {
+				// obj may be a cache.DeletedFinalStateUnknown if the watch missed
+				// the deletion; the queue's key function handles both.
+				klog.V(4).InfoS("Enqueue delete event", "object", obj)
+				c.providerConfigQueue.EnqueueWithPriority(obj, taskqueue.PriorityTerminating)
 			},
 		})
 
@@ -99,10 +290,52 @@ func newController(manager controllerManager, providerConfigInformer cache.Share
 	return c
 }
 
+// enqueuePriority returns the priority to sync obj with: terminating
+// ProviderConfigs come first so that their finalizer does not hold up the
+// deletion, otherwise def applies.
+func enqueuePriority(obj any, def taskqueue.Priority) taskqueue.Priority {
+	if pc, ok := obj.(*unstructured.Unstructured); ok && !pc.GetDeletionTimestamp().IsZero() {
+		return taskqueue.PriorityTerminating
+	}
+	return def
+}
+
+// updatePriority returns the priority to sync an updated ProviderConfig with.
+// Updates that may change which controllers run, i.e. spec, label and pause
+// changes, are prioritized over routine updates such as resyncs and status
+// changes.
+func updatePriority(old, cur any) taskqueue.Priority {
+	if p := enqueuePriority(cur, taskqueue.PriorityRoutine); p != taskqueue.PriorityRoutine {
+		return p
+	}
+	oldPC, ok := old.(*unstructured.Unstructured)
+	if !ok {
+		return taskqueue.PriorityNew
+	}
+	curPC, ok := cur.(*unstructured.Unstructured)
+	if !ok {
+		return taskqueue.PriorityRoutine
+	}
+	if oldPC.GetGeneration() != curPC.GetGeneration() || !maps.Equal(oldPC.GetLabels(), curPC.GetLabels()) || isPaused(oldPC) != isPaused(curPC) {
+		return taskqueue.PriorityNew
+	}
+	return taskqueue.PriorityRoutine
+}
+
 // Run starts the controller and blocks until the stop channel is closed.
+// With leader election enabled, Run also returns when leadership is lost.
+// Run returns right away, without processing any ProviderConfig, if the
+// options passed to New are invalid.
+// Before returning, Run stops the controllers of every tenant and waits for
+// them to exit, see WithShutdownTimeout.
 func (c *Controller) Run() {
 	defer c.shutdown()
 
+	if c.configErr != nil {
+		klog.ErrorS(c.configErr, "Refusing to start ProviderConfig Controller with an invalid configuration")
+		return
+	}
+
 	klog.InfoS("Starting ProviderConfig controller")
 
 	klog.InfoS("Waiting for initial cache sync before starting ProviderConfig Controller")
@@ -112,48 +345,98 @@ func (c *Controller) Run() {
 		return
 	}
 
+	if c.leaderElection != nil {
+		klog.InfoS("Waiting for leadership before starting ProviderConfig Controller workers")
+		c.runWithLeaderElection()
+		klog.InfoS("ProviderConfig Controller exited")
+		return
+	}
+
+	if c.sharder != nil {
+		klog.InfoS("Waiting for shard membership before starting ProviderConfig Controller workers", "group", c.sharder.group, "identity", c.sharder.identity)
+		c.runWithSharding()
+		klog.InfoS("ProviderConfig Controller exited")
+		return
+	}
+
 	klog.InfoS("Started ProviderConfig Controller", "numWorkers", c.workersCount)
 	c.providerConfigQueue.Run()
+	go c.runDriftReconciler(c.stopCh)
 
 	<-c.stopCh
+	// Drain the workers first so that no tenant is started concurrently, then
+	// stop every tenant so that its controllers do not outlive the Controller.
+	// Finalizers are kept, since the tenant objects still exist.
+	c.providerConfigQueue.Shutdown()
+	c.stopAllTenants("shutdown")
 	klog.InfoS("ProviderConfig Controller exited")
 }
 
 func (c *Controller) shutdown() {
 	klog.InfoS("Shutting down ProviderConfig Controller")
 	c.providerConfigQueue.Shutdown()
+	if c.eventBroadcaster != nil {
+		c.eventBroadcaster.Shutdown()
+	}
+}
+
+// stopAllTenants stops the controllers of every tenant in parallel and waits
+// for them to exit until the shutdown timeout expires. The tenants that did
+// not stop in time are logged. reason describes why the tenants are stopped.
+func (c *Controller) stopAllTenants(reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.shutdownTimeout)
+	defer cancel()
+	logger := klog.Background().WithValues("reason", reason)
+	start := c.clock.Now()
+	if err := c.manager.StopAllControllers(klog.NewContext(ctx, logger)); err != nil {
+		logger.Error(err, "Failed to stop all tenant controllers", "shutdownTimeout", c.shutdownTimeout)
+		return
+	}
+	logger.Info("Stopped all tenant controllers", "duration", c.clock.Since(start))
 }
 
+// syncWrapper syncs key, recovering from panics. The sync logs through a logger
+// derived from the one in ctx, which carries the worker ID, and passes it on
+// to the manager and the starters through ctx.
 func (c *Controller) syncWrapper(ctx context.Context, key string) (err error) {
-	// For cluster-scoped resources, the key is the resource name, which is the tenant UID.
-	tenantUID := key
 	syncID := rand.Int31()
+	logger := klog.FromContext(ctx).WithValues("providerConfig", key, "syncID", syncID)
+	ctx = klog.NewContext(ctx, logger)
+
+	// Registered first so that it also sees the error of a recovered panic.
+	defer func() {
+		c.recordSyncResult(key, err)
+	}()
 
 	defer func() {
 		if r := recover(); r != nil {
 			stack := string(debug.Stack())
-			klog.ErrorS(errors.New("panic in ProviderConfig sync worker goroutine"), "Recovered from panic", "panic", r, "stack", stack, "syncID", syncID, "tenant", tenantUID)
+			logger.Error(errors.New("panic in ProviderConfig sync worker goroutine"), "Recovered from panic", "panic", r, "stack", stack)
 			err = fmt.Errorf("panic in sync worker: %v", r)
 		}
 	}()
 
-	err = c.sync(ctx, key, syncID)
+	err = c.sync(ctx, key)
 	if err != nil {
-		klog.ErrorS(err, "Error syncing providerConfig", "key", key, "syncID", syncID, "tenant", tenantUID)
+		logger.Error(err, "Error syncing providerConfig")
 	}
 	return err
 }
 
-func (c *Controller) sync(ctx context.Context, key string, syncID int32) (err error) {
-	// Tenant UID is not available from context yet. The key itself is the tenant UID.
-	tenantUID := key
-
+func (c *Controller) sync(ctx context.Context, key string) (err error) {
+	logger := klog.FromContext(ctx)
 	obj, exists, err := c.providerConfigLister.GetByKey(key)
 	if err != nil {
 		return fmt.Errorf("failed to lookup providerConfig for key %s: %w", key, err)
 	}
 	if !exists || obj == nil {
-		klog.InfoS("ProviderConfig does not exist anymore", "key", key, "syncID", syncID, "tenant", tenantUID)
+		logger.Info("ProviderConfig does not exist anymore")
+		// Controllers may still be running if the ProviderConfig was deleted
+		// without going through the finalizer.
+		if err := c.manager.CleanupControllersForProviderConfig(ctx, key); err != nil {
+			return fmt.Errorf("failed to clean up controllers for deleted providerConfig %s: %w", key, err)
+		}
+		c.setPaused(key, false)
 		return nil
 	}
 
@@ -162,33 +445,60 @@ func (c *Controller) sync(ctx context.Context, key string, syncID int32) (err er
 		return fmt.Errorf("expected *unstructured.Unstructured but got %T", obj)
 	}
 
-	// The tenant UID is the name of the ProviderConfig.
-	if tenantUID != u.GetName() {
-		err := fmt.Errorf("mismatched tenant UID: %s != %s", tenantUID, u.GetName())
-		klog.ErrorS(err, "Mismatched tenant UID", "key", key, "pcName", u.GetName(), "syncID", syncID)
-		return err
+	tenantUID, err := c.tenants.tenantUID(u)
+	if err != nil {
+		if u.GetDeletionTimestamp().IsZero() {
+			logger.Error(err, "Failed to determine tenant UID")
+			return err
+		}
+		// A terminating ProviderConfig must still be able to stop its
+		// controllers and release its finalizer, so fall back to the UID
+		// recorded when they were started, if any.
+		tenantUID = c.manager.TenantUID(key)
+		logger.Error(err, "Failed to determine tenant UID of terminating ProviderConfig; using the recorded one", "recordedTenantUID", tenantUID)
 	}
 
 	// Populate tenant context
-	ctx = mtcontext.ContextWithTenantUID(ctx, u.GetName())
+	if tenantUID != "" {
+		logger = logger.WithValues("tenantUID", tenantUID)
+		ctx = klog.NewContext(mtcontext.ContextWithTenantUID(ctx, tenantUID), logger)
+	}
+
+	if c.sharder != nil {
+		u, err = c.syncShardOwnership(ctx, u)
+		if err != nil || u == nil {
+			return err
+		}
+	}
 
 	if !u.GetDeletionTimestamp().IsZero() {
-		klog.InfoS("ProviderConfig is being deleted, stopping controllers", "providerConfig", u, "syncID", syncID, "tenant", tenantUID)
+		logger.Info("ProviderConfig is being deleted, stopping controllers")
 
 		err := c.manager.StopControllersForProviderConfig(ctx, u)
 		if err != nil {
-			return fmt.Errorf("failed to stop controllers for providerConfig %s: %w", u.GetName(), err)
+			return fmt.Errorf("failed to stop controllers for providerConfig %s: %w", key, err)
+		}
+		c.setPaused(key, false)
+		return nil
+	}
+
+	if isPaused(u) {
+		logger.Info("ProviderConfig is paused, stopping controllers")
+		if err := c.manager.PauseControllersForProviderConfig(ctx, u); err != nil {
+			return fmt.Errorf("failed to pause controllers for providerConfig %s: %w", key, err)
 		}
+		c.setPaused(key, true)
 		return nil
 	}
+	c.setPaused(key, false)
 
-	klog.InfoS("Syncing providerConfig", "providerConfig", u, "syncID", syncID, "tenant", tenantUID)
+	logger.Info("Syncing providerConfig", "generation", u.GetGeneration())
 	err = c.manager.StartControllersForProviderConfig(ctx, u)
 	if err != nil {
-		return fmt.Errorf("failed to start controllers for providerConfig %s: %w", u.GetName(), err)
+		return fmt.Errorf("failed to start controllers for providerConfig %s: %w", key, err)
 	}
 
-	klog.InfoS("Successfully synced providerConfig", "providerConfig", u, "syncID", syncID, "tenant", tenantUID)
+	logger.Info("Successfully synced providerConfig")
 	return nil
 
 }
@@ -1,11 +1,218 @@
 package framework
 
-import "sync"
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 // ControllerSet holds controller-specific resources for a ProviderConfig.
-// It contains the stop channel used to signal controller shutdown.
+// It tracks the controllers of every ControllerStarter enabled for the
+// ProviderConfig, keyed by starter name.
 type ControllerSet struct {
+	// mu guards the controllers map and tenantUID. It is held while the
+	// starters are inspected from outside of the manager, e.g. by the drift
+	// reconciler or the debug handler, and is acquired before the mu of any
+	// starterControllers.
+	mu          sync.Mutex
+	controllers map[string]*starterControllers
+	// tenantUID is the UID of the tenant, recorded when its controllers are
+	// started so that it is known after the tenant object is gone.
+	tenantUID string
+	// readyReported is only accessed by the manager, which never processes the
+	// same ProviderConfig concurrently. It is set once the ControllersReady
+	// condition reported the running controllers as ready.
+	readyReported bool
+	// degraded is set when the controllers of a starter panicked, and cleared
+	// once the restarted controllers are ready.
+	degraded atomic.Bool
+}
+
+// starterControllers holds the controllers started by one named ControllerStarter.
+// It contains the stop channel used to signal controller shutdown and the
+// done channel used to observe it.
+//
+// The manager accesses it only while it processes the ProviderConfig, which
+// it never does concurrently. The fields that are also read outside of the
+// manager, stopCh, startedAt, readiness and panicked, are written with mu
+// held; the manager reads them without it.
+type starterControllers struct {
+	mu     sync.Mutex
 	stopCh chan<- struct{}
+	// cancel cancels the context passed to a ContextControllerStarter. It is
+	// nil for other starters.
+	cancel context.CancelFunc
+	// done is closed by the starter once the controllers have exited. It is nil
+	// for starters that cannot report exit.
+	done <-chan struct{}
+	// stopRequested is when stopCh was closed. It is zero while the controllers are running.
+	stopRequested time.Time
+	// generation and specHash identify the ProviderConfig spec the controllers
+	// were started with, or last updated to.
+	generation int64
+	specHash   string
+	// stopSignal is closed together with stopCh so that the exit watcher can
+	// tell a requested stop from a crash.
+	stopSignal chan struct{}
+	// startedAt is when the running controllers were started.
+	startedAt time.Time
+	// crashes counts consecutive unexpected exits and drives the restart backoff.
+	crashes int
+	// restartAt is the earliest time controllers that exited unexpectedly may be restarted.
+	restartAt time.Time
+	// restarts counts every restart after an unexpected exit.
+	restarts atomic.Int64
+	// readiness tracks whether the running controllers are ready. A new one is
+	// created on every start, so that a stale readiness watcher cannot mark
+	// restarted controllers ready.
+	readiness *readiness
+	// panicked is set when a goroutine of the running controllers, started
+	// through Go, panicked. A new one is created on every start.
+	panicked *atomic.Bool
+	// panics counts the panics recovered in the controllers of the starter and
+	// lastPanic is the last of them, across restarts.
+	panics    atomic.Int64
+	lastPanic atomic.Pointer[tenantPanic]
+}
+
+// readiness records when the controllers of one start became ready.
+type readiness struct {
+	readyAt atomic.Pointer[time.Time]
+}
+
+// markReady records that the controllers became ready at the given time.
+func (r *readiness) markReady(at time.Time) {
+	r.readyAt.Store(&at)
+}
+
+// ready reports whether the controllers are ready. A nil readiness is never ready.
+func (r *readiness) ready() bool {
+	return r != nil && r.readyAt.Load() != nil
+}
+
+// controllersFor returns the controllers tracked for the named starter,
+// creating an empty entry when absent.
+func (cs *ControllerSet) controllersFor(name string) *starterControllers {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.controllers == nil {
+		cs.controllers = make(map[string]*starterControllers)
+	}
+	sc, ok := cs.controllers[name]
+	if !ok {
+		sc = &starterControllers{}
+		cs.controllers[name] = sc
+	}
+	return sc
+}
+
+// removeControllers stops tracking the controllers of the named starter.
+func (cs *ControllerSet) removeControllers(name string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	delete(cs.controllers, name)
+}
+
+// Starters returns the sorted names of the starters tracked for the ProviderConfig.
+func (cs *ControllerSet) Starters() []string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	names := make([]string, 0, len(cs.controllers))
+	for name := range cs.controllers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Restarts returns how many times the controllers of the named starter were
+// restarted after exiting unexpectedly. It is safe to call concurrently with
+// the manager.
+func (cs *ControllerSet) Restarts(name string) int64 {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	sc, ok := cs.controllers[name]
+	if !ok {
+		return 0
+	}
+	return sc.restarts.Load()
+}
+
+// setTenantUID records the UID of the tenant.
+func (cs *ControllerSet) setTenantUID(tenantUID string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.tenantUID = tenantUID
+}
+
+// getTenantUID returns the UID of the tenant, or "" if it is not known.
+func (cs *ControllerSet) getTenantUID() string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.tenantUID
+}
+
+// running reports whether any starter has controllers that were not asked to stop.
+func (cs *ControllerSet) running() bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	for _, sc := range cs.controllers {
+		if sc.isRunning() {
+			return true
+		}
+	}
+	return false
+}
+
+// ready reports whether every starter with running controllers is ready.
+func (cs *ControllerSet) ready() bool {
+	return len(cs.notReady()) == 0
+}
+
+// notReady returns the sorted names of the starters whose running controllers
+// are not ready yet. Controllers that panicked are not ready.
+func (cs *ControllerSet) notReady() []string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	var names []string
+	for name, sc := range cs.controllers {
+		sc.mu.Lock()
+		if sc.stopCh != nil && (!sc.readiness.ready() || sc.hasPanicked()) {
+			names = append(names, name)
+		}
+		sc.mu.Unlock()
+	}
+	slices.Sort(names)
+	return names
+}
+
+// isRunning reports whether the controllers were started and not asked to stop.
+// It is safe to call outside of the manager.
+func (sc *starterControllers) isRunning() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.stopCh != nil
+}
+
+// hasPanicked reports whether a goroutine of the running controllers panicked.
+func (sc *starterControllers) hasPanicked() bool {
+	return sc.stopCh != nil && sc.panicked != nil && sc.panicked.Load()
+}
+
+// exitedUnexpectedly reports whether the running controllers closed their done
+// channel although the framework never asked them to stop.
+func (sc *starterControllers) exitedUnexpectedly() bool {
+	if sc.stopCh == nil || sc.done == nil {
+		return false
+	}
+	select {
+	case <-sc.done:
+		return true
+	default:
+		return false
+	}
 }
 
 // ControllerMap is a thread-safe map for storing ControllerSet instances.
@@ -44,6 +251,18 @@ func (cm *ControllerMap) GetOrCreate(key string) (*ControllerSet, bool) {
 	return cs, false
 }
 
+// Keys returns the sorted keys of all ControllerSets.
+func (cm *ControllerMap) Keys() []string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	keys := make([]string, 0, len(cm.data))
+	for key := range cm.data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
 // Delete removes the ControllerSet for the given key.
 func (cm *ControllerMap) Delete(key string) {
 	cm.mu.Lock()
@@ -4,6 +4,7 @@ package framework
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -12,10 +13,18 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/tools/cache"
+
+	"github.com/go-logr/logr"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/klog/v2"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework/taskqueue"
 )
 
 var (
@@ -29,20 +38,35 @@ type fakePCManager struct {
 	mu             sync.Mutex
 	startedConfigs map[string]*unstructured.Unstructured
 	stoppedConfigs map[string]*unstructured.Unstructured
+	// releasedConfigs are the ProviderConfigs whose controllers were handed off.
+	releasedConfigs map[string]*unstructured.Unstructured
+	// releasing makes ReleaseControllersForProviderConfig report that the
+	// controllers are still stopping.
+	releasing bool
+	// pausedConfigs are the ProviderConfigs whose controllers were paused.
+	pausedConfigs map[string]*unstructured.Unstructured
+	// cleanedUpKeys are the keys of deleted ProviderConfigs that were cleaned up.
+	cleanedUpKeys []string
+	// pendingKeys are the keys reported as pending admission.
+	pendingKeys sets.Set[string]
 
 	startErr error // optional injected error
 	stopErr  error // optional injected error
 
+	stopAllCalls int
+
 	client        dynamic.Interface
 	finalizerName string
 }
 
 func newFakeProviderConfigControllersManager(client dynamic.Interface, finalizerName string) *fakePCManager {
 	return &fakePCManager{
-		startedConfigs: make(map[string]*unstructured.Unstructured),
-		stoppedConfigs: make(map[string]*unstructured.Unstructured),
-		client:         client,
-		finalizerName:  finalizerName,
+		startedConfigs:  make(map[string]*unstructured.Unstructured),
+		stoppedConfigs:  make(map[string]*unstructured.Unstructured),
+		releasedConfigs: make(map[string]*unstructured.Unstructured),
+		pausedConfigs:   make(map[string]*unstructured.Unstructured),
+		client:          client,
+		finalizerName:   finalizerName,
 	}
 }
 
@@ -72,6 +96,99 @@ func (f *fakePCManager) StopControllersForProviderConfig(ctx context.Context, pc
 	return nil
 }
 
+func (f *fakePCManager) StopAllControllers(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopAllCalls++
+	return nil
+}
+
+func (f *fakePCManager) ReleaseControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.releasedConfigs[pc.GetName()] = pc
+	if f.releasing {
+		return false
+	}
+	delete(f.startedConfigs, pc.GetName())
+	return true
+}
+
+func (f *fakePCManager) CleanupControllersForProviderConfig(ctx context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cleanedUpKeys = append(f.cleanedUpKeys, key)
+	return nil
+}
+
+func (f *fakePCManager) PauseControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pausedConfigs[pc.GetName()] = pc
+	delete(f.startedConfigs, pc.GetName())
+	return nil
+}
+
+func (f *fakePCManager) HasPaused(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.pausedConfigs[name]
+	return ok
+}
+
+func (f *fakePCManager) TrackedProviderConfigs() map[string]bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tracked := map[string]bool{}
+	for name := range f.startedConfigs {
+		tracked[name] = true
+	}
+	return tracked
+}
+
+func (f *fakePCManager) PendingProviderConfigs() sets.Set[string] {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pendingKeys.Clone()
+}
+
+func (f *fakePCManager) TenantUID(key string) string {
+	return ""
+}
+
+func (f *fakePCManager) DebugTenants() map[string]TenantDebugInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tenants := map[string]TenantDebugInfo{}
+	for name := range f.startedConfigs {
+		tenants[name] = TenantDebugInfo{Key: name, State: TenantStateRunning}
+	}
+	return tenants
+}
+
+func (f *fakePCManager) HasFinalizer(pc *unstructured.Unstructured) bool {
+	return slices.Contains(pc.GetFinalizers(), f.finalizerName)
+}
+
+func (f *fakePCManager) CleanedUpKeys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.cleanedUpKeys)
+}
+
+func (f *fakePCManager) HasReleased(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.releasedConfigs[name]
+	return ok
+}
+
+func (f *fakePCManager) StopAllCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stopAllCalls
+}
+
 func (f *fakePCManager) HasStarted(name string) bool {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -219,6 +336,27 @@ func updateProviderConfig(t *testing.T, tc *testProviderConfigController, pc *un
 	}
 }
 
+// deleteProviderConfig removes the ProviderConfig without a deletion timestamp,
+// as a force deletion does. With tombstone set, the delete event carries a
+// cache.DeletedFinalStateUnknown, as when the watch missed the deletion.
+func deleteProviderConfig(t *testing.T, tc *testProviderConfigController, pc *unstructured.Unstructured, tombstone bool) {
+	t.Helper()
+	if err := tc.pcClient.Resource(testProviderConfigGVR).Delete(context.TODO(), pc.GetName(), metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("failed to delete ProviderConfig: %v", err)
+	}
+	if err := tc.pcInformer.GetIndexer().Delete(pc); err != nil {
+		t.Fatalf("failed to delete ProviderConfig from indexer: %v", err)
+	}
+	if tc.pcInformer.handler == nil {
+		return
+	}
+	var obj any = pc
+	if tombstone {
+		obj = cache.DeletedFinalStateUnknown{Key: pc.GetName(), Obj: pc}
+	}
+	tc.pcInformer.handler.OnDelete(obj)
+}
+
 // TestStartAndStop verifies that the controller starts and stops gracefully when stopCh is closed.
 func TestStartAndStop(t *testing.T) {
 	tc := newTestProviderConfigController(t)
@@ -362,6 +500,230 @@ func TestSyncNonExistent(t *testing.T) {
 	}
 }
 
+// TestDeleteEventCleansUpControllers verifies that ProviderConfigs that disappear
+// without a deletion timestamp have their controllers cleaned up, including when
+// the delete event is a tombstone.
+func TestDeleteEventCleansUpControllers(t *testing.T) {
+	for _, tombstone := range []bool{false, true} {
+		t.Run(fmt.Sprintf("tombstone=%v", tombstone), func(t *testing.T) {
+			tc := newTestProviderConfigController(t)
+			go tc.pcController.Run()
+			defer close(tc.stopCh)
+
+			pc := &unstructured.Unstructured{
+				Object: map[string]any{
+					"apiVersion": "cloud.gke.io/v1",
+					"kind":       "ProviderConfig",
+					"metadata": map[string]any{
+						"name": "pc-force-deleted",
+					},
+				},
+			}
+			addProviderConfig(t, tc, pc)
+			if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+				return tc.manager.HasStarted("pc-force-deleted"), nil
+			}); err != nil {
+				t.Fatalf("Expected manager to have started 'pc-force-deleted' within timeout: %v", err)
+			}
+
+			deleteProviderConfig(t, tc, pc, tombstone)
+
+			if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+				return slices.Contains(tc.manager.CleanedUpKeys(), "pc-force-deleted"), nil
+			}); err != nil {
+				t.Errorf("Expected controllers of 'pc-force-deleted' to be cleaned up within timeout: %v", err)
+			}
+			if tc.manager.HasStopped("pc-force-deleted") {
+				t.Errorf("Did not expect finalizer handling for a ProviderConfig that no longer exists")
+			}
+		})
+	}
+}
+
+// TestDriftReconciliation verifies that the drift reconciler enqueues live
+// ProviderConfigs without controllers, controllers without a ProviderConfig and
+// running controllers whose ProviderConfig lost its finalizer, and counts each
+// correction.
+func TestDriftReconciliation(t *testing.T) {
+	tc := newTestProviderConfigController(t)
+	defer close(tc.stopCh)
+
+	newPC := func(name string, finalizers ...string) *unstructured.Unstructured {
+		pc := &unstructured.Unstructured{
+			Object: map[string]any{
+				"apiVersion": "cloud.gke.io/v1",
+				"kind":       "ProviderConfig",
+				"metadata": map[string]any{
+					"name": name,
+				},
+			},
+		}
+		pc.SetFinalizers(finalizers)
+		return pc
+	}
+	terminating := newPC("pc-terminating", "test-finalizer")
+	terminating.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	for _, pc := range []*unstructured.Unstructured{
+		newPC("pc-missing"),
+		newPC("pc-pending"),
+		newPC("pc-healthy", "test-finalizer"),
+		newPC("pc-no-finalizer"),
+		terminating,
+	} {
+		if err := tc.pcInformer.GetIndexer().Add(pc); err != nil {
+			t.Fatalf("Failed to add ProviderConfig to indexer: %v", err)
+		}
+	}
+	for _, name := range []string{"pc-healthy", "pc-no-finalizer", "pc-orphaned"} {
+		tc.manager.startedConfigs[name] = newPC(name)
+	}
+	// The start of pc-pending waits for admission and is retried by its own requeue.
+	tc.manager.pendingKeys = sets.New("pc-pending")
+
+	tc.pcController.reconcileDrift()
+
+	for kind, want := range map[string]float64{
+		driftMissingTenant:    1,
+		driftOrphanedTenant:   1,
+		driftMissingFinalizer: 1,
+	} {
+		m := &dto.Metric{}
+		if err := tc.pcController.metrics.driftCorrections.WithLabelValues(kind).Write(m); err != nil {
+			t.Fatalf("Failed to read drift metric: %v", err)
+		}
+		if got := m.GetCounter().GetValue(); got != want {
+			t.Errorf("Drift corrections of kind %s = %v, want %v", kind, got, want)
+		}
+	}
+	if got := tc.pcController.providerConfigQueue.Len(); got != 3 {
+		t.Errorf("Expected 3 enqueued ProviderConfigs, got %d", got)
+	}
+
+	go tc.pcController.Run()
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return tc.manager.HasStarted("pc-missing") && slices.Contains(tc.manager.CleanedUpKeys(), "pc-orphaned"), nil
+	}); err != nil {
+		t.Errorf("Expected drift to be corrected within timeout: %v", err)
+	}
+}
+
+// TestPausedAnnotation verifies that annotating a ProviderConfig pauses its
+// controllers without going through the finalizer, and that removing the
+// annotation starts them again.
+func TestPausedAnnotation(t *testing.T) {
+	tc := newTestProviderConfigController(t)
+	go tc.pcController.Run()
+	defer close(tc.stopCh)
+
+	pc := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "cloud.gke.io/v1",
+			"kind":       "ProviderConfig",
+			"metadata": map[string]any{
+				"name": "pc-paused",
+			},
+		},
+	}
+	addProviderConfig(t, tc, pc)
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return tc.manager.HasStarted("pc-paused"), nil
+	}); err != nil {
+		t.Fatalf("Expected manager to have started 'pc-paused' within timeout: %v", err)
+	}
+
+	pausedTenants := func() float64 {
+		m := &dto.Metric{}
+		if err := tc.pcController.metrics.pausedTenants.Write(m); err != nil {
+			t.Fatalf("Failed to read paused tenants metric: %v", err)
+		}
+		return m.GetGauge().GetValue()
+	}
+
+	paused := pc.DeepCopy()
+	paused.SetAnnotations(map[string]string{PausedAnnotation: "true"})
+	updateProviderConfig(t, tc, paused)
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return tc.manager.HasPaused("pc-paused") && pausedTenants() == 1, nil
+	}); err != nil {
+		t.Fatalf("Expected 'pc-paused' to be paused within timeout: %v", err)
+	}
+	if tc.manager.HasStopped("pc-paused") {
+		t.Errorf("Did not expect finalizer handling for a paused ProviderConfig")
+	}
+
+	updateProviderConfig(t, tc, pc)
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return tc.manager.HasStarted("pc-paused") && pausedTenants() == 0, nil
+	}); err != nil {
+		t.Errorf("Expected 'pc-paused' to be resumed within timeout: %v", err)
+	}
+}
+
+// TestUpdatePriority verifies that terminating ProviderConfigs and updates
+// that may change the running controllers are prioritized over routine updates.
+func TestUpdatePriority(t *testing.T) {
+	newPC := func(mutate func(pc *unstructured.Unstructured)) *unstructured.Unstructured {
+		pc := &unstructured.Unstructured{
+			Object: map[string]any{
+				"apiVersion": "cloud.gke.io/v1",
+				"kind":       "ProviderConfig",
+				"metadata": map[string]any{
+					"name":       "pc",
+					"generation": int64(1),
+				},
+			},
+		}
+		if mutate != nil {
+			mutate(pc)
+		}
+		return pc
+	}
+	testCases := []struct {
+		desc string
+		old  any
+		cur  any
+		want taskqueue.Priority
+	}{
+		{
+			desc: "resync",
+			old:  newPC(nil),
+			cur:  newPC(nil),
+			want: taskqueue.PriorityRoutine,
+		},
+		{
+			desc: "spec change",
+			old:  newPC(nil),
+			cur:  newPC(func(pc *unstructured.Unstructured) { pc.SetGeneration(2) }),
+			want: taskqueue.PriorityNew,
+		},
+		{
+			desc: "label change",
+			old:  newPC(nil),
+			cur:  newPC(func(pc *unstructured.Unstructured) { pc.SetLabels(map[string]string{"tier": "gold"}) }),
+			want: taskqueue.PriorityNew,
+		},
+		{
+			desc: "paused",
+			old:  newPC(nil),
+			cur:  newPC(func(pc *unstructured.Unstructured) { pc.SetAnnotations(map[string]string{PausedAnnotation: "true"}) }),
+			want: taskqueue.PriorityNew,
+		},
+		{
+			desc: "deletion requested",
+			old:  newPC(nil),
+			cur:  newPC(func(pc *unstructured.Unstructured) { pc.SetDeletionTimestamp(&metav1.Time{Time: time.Now()}) }),
+			want: taskqueue.PriorityTerminating,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := updatePriority(tc.old, tc.cur); got != tc.want {
+				t.Errorf("updatePriority() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
 // TestSyncBadObjectType ensures that if we get an unexpected type out of the indexer,
 // we log an error but skip it.
 func TestSyncBadObjectType(t *testing.T) {
@@ -426,6 +788,42 @@ func (f *fakePanickingManager) StopControllersForProviderConfig(ctx context.Cont
 	return nil
 }
 
+func (f *fakePanickingManager) StopAllControllers(ctx context.Context) error {
+	return nil
+}
+
+func (f *fakePanickingManager) ReleaseControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) bool {
+	return true
+}
+
+func (f *fakePanickingManager) CleanupControllersForProviderConfig(ctx context.Context, key string) error {
+	return nil
+}
+
+func (f *fakePanickingManager) PauseControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) error {
+	return nil
+}
+
+func (f *fakePanickingManager) TrackedProviderConfigs() map[string]bool {
+	return nil
+}
+
+func (f *fakePanickingManager) PendingProviderConfigs() sets.Set[string] {
+	return nil
+}
+
+func (f *fakePanickingManager) TenantUID(key string) string {
+	return ""
+}
+
+func (f *fakePanickingManager) DebugTenants() map[string]TenantDebugInfo {
+	return nil
+}
+
+func (f *fakePanickingManager) HasFinalizer(pc *unstructured.Unstructured) bool {
+	return false
+}
+
 func (f *fakePanickingManager) getPanicCount(name string) int {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -529,3 +927,232 @@ func TestPanicRecovery(t *testing.T) {
 
 	t.Log("Controller survived panic and continued processing")
 }
+
+// TestNewControllerOptions verifies that valid options tune the controller and
+// invalid ones leave the defaults in place and make the controller refuse to
+// run.
+func TestNewControllerOptions(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		opts        []Option
+		wantWorkers int
+		wantErr     bool
+	}{
+		{
+			desc:        "defaults",
+			wantWorkers: workersCount,
+		},
+		{
+			desc:        "custom worker count",
+			opts:        []Option{WithWorkers(20)},
+			wantWorkers: 20,
+		},
+		{
+			desc:        "invalid worker count keeps default",
+			opts:        []Option{WithWorkers(0)},
+			wantWorkers: workersCount,
+			wantErr:     true,
+		},
+		{
+			desc:        "invalid queue options are rejected",
+			opts:        []Option{WithQueueOptions(taskqueue.WithMaxRequeues(-1))},
+			wantWorkers: workersCount,
+			wantErr:     true,
+		},
+		{
+			desc:        "queue key function is rejected",
+			opts:        []Option{WithQueueOptions(taskqueue.WithKeyFunc(taskqueue.KeyFunc))},
+			wantWorkers: workersCount,
+			wantErr:     true,
+		},
+		{
+			desc:        "queue clock is rejected",
+			opts:        []Option{WithQueueOptions(taskqueue.WithClock(testingclock.NewFakeClock(time.Now())))},
+			wantWorkers: workersCount,
+			wantErr:     true,
+		},
+		{
+			desc:        "invalid restart backoff is rejected",
+			opts:        []Option{WithRestartBackoff(time.Minute, time.Second)},
+			wantWorkers: workersCount,
+			wantErr:     true,
+		},
+		{
+			desc:        "invalid start rate limit is rejected",
+			opts:        []Option{WithStartRateLimit(10, 0)},
+			wantWorkers: workersCount,
+			wantErr:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := newOptions(tc.opts...).validate(); (err != nil) != tc.wantErr {
+				t.Errorf("validate() = %v, wantErr %v", err, tc.wantErr)
+			}
+			fakeInformer := &fakeInformer{
+				Indexer: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}),
+				synced:  true,
+			}
+			c := newController(&fakePCManager{}, fakeInformer, make(chan struct{}), append(tc.opts, WithQueueName(""))...)
+			if c.workersCount != tc.wantWorkers {
+				t.Errorf("workersCount = %d, want %d", c.workersCount, tc.wantWorkers)
+			}
+			if (c.configErr != nil) != tc.wantErr {
+				t.Errorf("configErr = %v, wantErr %v", c.configErr, tc.wantErr)
+			}
+			if q, ok := c.providerConfigQueue.(*taskqueue.PeriodicTaskQueueWithMultipleWorkers); !ok || q == nil {
+				t.Error("Expected the ProviderConfig queue to be created")
+			}
+		})
+	}
+}
+
+// TestNewValidated verifies that NewValidated returns invalid options as an
+// error instead of a Controller.
+func TestNewValidated(t *testing.T) {
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	newInformer := func() *fakeInformer {
+		return &fakeInformer{Indexer: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}), synced: true}
+	}
+
+	ctrl, err := NewValidated(dynamicClient, newInformer(), "test-finalizer", newMockControllerStarter(), make(chan struct{}), WithWorkers(0))
+	if err == nil {
+		t.Error("Expected an error for an invalid worker count")
+	}
+	if ctrl != nil {
+		t.Error("Did not expect a Controller for invalid options")
+	}
+
+	ctrl, err = NewValidated(dynamicClient, newInformer(), "test-finalizer", newMockControllerStarter(), make(chan struct{}), WithWorkers(2))
+	if err != nil {
+		t.Fatalf("NewValidated failed: %v", err)
+	}
+	if ctrl.workersCount != 2 {
+		t.Errorf("workersCount = %d, want 2", ctrl.workersCount)
+	}
+}
+
+// valuesSink is a logr.LogSink that only records the values added to it.
+type valuesSink struct {
+	values []any
+}
+
+func (s *valuesSink) Init(logr.RuntimeInfo)        {}
+func (s *valuesSink) Enabled(int) bool             { return false }
+func (s *valuesSink) Info(int, string, ...any)     {}
+func (s *valuesSink) Error(error, string, ...any)  {}
+func (s *valuesSink) WithName(string) logr.LogSink { return s }
+func (s *valuesSink) WithValues(kvs ...any) logr.LogSink {
+	return &valuesSink{values: append(slices.Clone(s.values), kvs...)}
+}
+
+// loggerValues returns the values of the logger in ctx as a map.
+func loggerValues(t *testing.T, ctx context.Context) map[string]any {
+	t.Helper()
+	sink, ok := klog.FromContext(ctx).GetSink().(*valuesSink)
+	if !ok {
+		t.Fatalf("Expected the context to carry the test logger, got %T", klog.FromContext(ctx).GetSink())
+	}
+	values := map[string]any{}
+	for i := 0; i+1 < len(sink.values); i += 2 {
+		values[fmt.Sprint(sink.values[i])] = sink.values[i+1]
+	}
+	return values
+}
+
+// TestSyncLogger verifies that the logger of a sync is scoped to the tenant
+// and the sync, and that context starters inherit it.
+func TestSyncLogger(t *testing.T) {
+	ctx := klog.NewContext(context.Background(), logr.New(&valuesSink{values: []any{"workerID", 3}}))
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	starter := newContextControllerStarter()
+	ctrl := New(dynamicClient, &fakeInformer{Indexer: indexer, synced: true}, "test-finalizer", AdaptContextControllerStarter(starter), make(chan struct{}))
+
+	pc := createTestProviderConfig("pc-logger")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := indexer.Add(pc); err != nil {
+		t.Fatalf("Failed to add ProviderConfig to indexer: %v", err)
+	}
+	if err := ctrl.syncWrapper(ctx, "pc-logger"); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	starterCtx, _ := starter.get("pc-logger")
+	if starterCtx == nil {
+		t.Fatal("Expected StartControllerWithContext to be called")
+	}
+	values := loggerValues(t, starterCtx)
+	want := map[string]any{
+		"workerID":       3,
+		"providerConfig": "pc-logger",
+		"tenantUID":      "pc-logger",
+		"starter":        DefaultControllerStarterName,
+	}
+	for k, v := range want {
+		if values[k] != v {
+			t.Errorf("Starter logger has %s=%v, want %v", k, values[k], v)
+		}
+	}
+	if _, ok := values["syncID"]; !ok {
+		t.Errorf("Expected the starter logger to carry the sync ID, got %v", values)
+	}
+}
+
+// TestRunStopsTenantsOnShutdown verifies that the controllers of every tenant
+// are stopped, and their finalizers kept, when the Controller shuts down.
+func TestRunStopsTenantsOnShutdown(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	starter := newHandleControllerStarter()
+	stopCh := make(chan struct{})
+	ctrl := New(dynamicClient, &fakeInformer{Indexer: indexer, synced: true}, "test-finalizer", starter, stopCh,
+		WithShutdownTimeout(time.Second),
+		WithDriftReconcileInterval(0),
+	)
+
+	pc := createTestProviderConfig("pc-shutdown")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := indexer.Add(pc); err != nil {
+		t.Fatalf("Failed to add ProviderConfig to indexer: %v", err)
+	}
+	if err := ctrl.syncWrapper(ctx, "pc-shutdown"); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	tenantStop, tenantDone := starter.channels("pc-shutdown")
+	go func() {
+		<-tenantStop
+		close(tenantDone)
+	}()
+
+	runDone := make(chan struct{})
+	go func() {
+		defer close(runDone)
+		ctrl.Run()
+	}()
+	close(stopCh)
+	select {
+	case <-runDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for Run to return")
+	}
+
+	if !isClosed(tenantDone) {
+		t.Error("Expected the tenant controllers to have exited")
+	}
+	if tracked := ctrl.manager.TrackedProviderConfigs(); len(tracked) != 0 {
+		t.Errorf("Expected no tracked tenants after shutdown, got %v", tracked)
+	}
+	updated, err := providerConfigFromClient(ctx, dynamicClient, "pc-shutdown")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if !hasFinalizer(updated, "test-finalizer") {
+		t.Error("Expected the finalizer to be kept on shutdown")
+	}
+}
@@ -0,0 +1,323 @@
+package framework
+
+import (
+	"cmp"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"slices"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework/taskqueue"
+)
+
+// TenantState summarizes the controllers of a tenant.
+type TenantState string
+
+const (
+	// TenantStateRunning means that controllers are running for the tenant and
+	// all of them are ready.
+	TenantStateRunning TenantState = "Running"
+	// TenantStateStarting means that controllers are running for the tenant but
+	// some of them are not ready yet, see ReadinessReporter.
+	TenantStateStarting TenantState = "Starting"
+	// TenantStateDegraded means that a goroutine of the controllers of the
+	// tenant panicked, see Go, and they are not ready again since.
+	TenantStateDegraded TenantState = "Degraded"
+	// TenantStateStopped means that the controllers of the tenant are tracked
+	// but not running, e.g. because they are stopping or waiting for a restart.
+	TenantStateStopped TenantState = "Stopped"
+	// TenantStatePaused means that the controllers of the tenant are paused
+	// through PausedAnnotation.
+	TenantStatePaused TenantState = "Paused"
+	// TenantStateNotStarted means that no controllers are tracked for the
+	// tenant, e.g. because its start is pending or failed.
+	TenantStateNotStarted TenantState = "NotStarted"
+)
+
+// TenantDebugInfo describes a tenant known to the framework.
+type TenantDebugInfo struct {
+	// Key is the queue key of the tenant.
+	Key string `json:"key"`
+	// TenantUID is the UID the controllers of the tenant were started with.
+	TenantUID string      `json:"tenantUID,omitempty"`
+	State     TenantState `json:"state"`
+	// Exists is false if the tenant object is no longer in the informer cache.
+	Exists bool `json:"exists"`
+	// Starters describes the controllers of every starter tracked for the tenant.
+	Starters []StarterDebugInfo `json:"starters,omitempty"`
+	// LastSyncError is the error of the last sync, if it failed.
+	LastSyncError     string     `json:"lastSyncError,omitempty"`
+	LastSyncErrorTime *time.Time `json:"lastSyncErrorTime,omitempty"`
+	// Requeues is how many times the tenant was requeued after failed syncs.
+	Requeues int `json:"requeues"`
+}
+
+// StarterDebugInfo describes the controllers started by one named starter.
+type StarterDebugInfo struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+	// StartedAt is when the running controllers were started.
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	// Ready reports whether the running controllers are ready, and ReadyAt
+	// when they became ready.
+	Ready   bool       `json:"ready"`
+	ReadyAt *time.Time `json:"readyAt,omitempty"`
+	// Restarts counts the restarts after unexpected exits.
+	Restarts int64 `json:"restarts"`
+	// Panics counts the panics recovered in goroutines of the controllers, and
+	// LastPanic, LastPanicStack and LastPanicTime describe the last of them.
+	Panics         int64      `json:"panics"`
+	LastPanic      string     `json:"lastPanic,omitempty"`
+	LastPanicStack string     `json:"lastPanicStack,omitempty"`
+	LastPanicTime  *time.Time `json:"lastPanicTime,omitempty"`
+}
+
+// DebugConfig configures the handler returned by Controller.DebugHandler.
+type DebugConfig struct {
+	// EnableActions allows POST requests that act on tenants, e.g. requeue.
+	// The handler is read-only otherwise.
+	EnableActions bool
+}
+
+// syncError is the error of the last failed sync of a key.
+type syncError struct {
+	message string
+	time    time.Time
+}
+
+// recordSyncResult remembers the error of a failed sync of key, and forgets
+// it once key syncs successfully.
+func (c *Controller) recordSyncResult(key string, err error) {
+	c.syncErrorsMu.Lock()
+	defer c.syncErrorsMu.Unlock()
+	if err == nil {
+		delete(c.syncErrors, key)
+		return
+	}
+	c.syncErrors[key] = syncError{message: err.Error(), time: c.clock.Now()}
+}
+
+// debugInfo describes the tenant with the given key tracked in cs. The state
+// and the starters are read in one pass with cs.mu and the mutex of every
+// starter held, so that they are consistent with each other even while the
+// manager starts or stops controllers.
+func (cs *ControllerSet) debugInfo(key string) TenantDebugInfo {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	starters := make([]StarterDebugInfo, 0, len(cs.controllers))
+	for name, sc := range cs.controllers {
+		sc.mu.Lock()
+		info := StarterDebugInfo{
+			Name:     name,
+			Running:  sc.stopCh != nil,
+			Restarts: sc.restarts.Load(),
+			Panics:   sc.panics.Load(),
+		}
+		if p := sc.lastPanic.Load(); p != nil {
+			info.LastPanic = p.message
+			info.LastPanicStack = p.stack
+			panicTime := p.time
+			info.LastPanicTime = &panicTime
+		}
+		if info.Running && !sc.startedAt.IsZero() {
+			startedAt := sc.startedAt
+			info.StartedAt = &startedAt
+		}
+		if info.Running && sc.readiness != nil {
+			info.ReadyAt = sc.readiness.readyAt.Load()
+			info.Ready = info.ReadyAt != nil && !sc.hasPanicked()
+		}
+		sc.mu.Unlock()
+		starters = append(starters, info)
+	}
+	slices.SortFunc(starters, func(a, b StarterDebugInfo) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	running := slices.ContainsFunc(starters, func(s StarterDebugInfo) bool { return s.Running })
+	ready := !slices.ContainsFunc(starters, func(s StarterDebugInfo) bool { return s.Running && !s.Ready })
+	state := TenantStateStopped
+	switch {
+	case cs.degraded.Load():
+		state = TenantStateDegraded
+	case running && ready:
+		state = TenantStateRunning
+	case running:
+		state = TenantStateStarting
+	}
+	return TenantDebugInfo{
+		Key:       key,
+		TenantUID: cs.tenantUID,
+		State:     state,
+		Starters:  starters,
+	}
+}
+
+// DebugTenants describes the controllers tracked for every ProviderConfig,
+// keyed by ProviderConfig key. It is safe to call outside of the manager.
+func (m *manager) DebugTenants() map[string]TenantDebugInfo {
+	tenants := map[string]TenantDebugInfo{}
+	for _, key := range m.controllers.Keys() {
+		if cs, ok := m.controllers.Get(key); ok {
+			tenants[key] = cs.debugInfo(key)
+		}
+	}
+	return tenants
+}
+
+// Tenants describes every tenant known to the controller: the tenants in the
+// informer cache and the tenants whose controllers are tracked by the manager.
+// They are sorted by key.
+func (c *Controller) Tenants() []TenantDebugInfo {
+	tenants := c.collectTenants()
+	keys := make([]string, 0, len(tenants))
+	for key := range tenants {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	result := make([]TenantDebugInfo, 0, len(keys))
+	for _, key := range keys {
+		info := tenants[key]
+		info.Requeues = c.providerConfigQueue.NumRequeues(cache.ExplicitKey(key))
+		result = append(result, info)
+	}
+	return result
+}
+
+// collectTenants describes the tenants known to the controller by key,
+// without their requeue counts.
+func (c *Controller) collectTenants() map[string]TenantDebugInfo {
+	tenants := c.manager.DebugTenants()
+	if tenants == nil {
+		tenants = map[string]TenantDebugInfo{}
+	}
+	for _, key := range c.providerConfigLister.ListKeys() {
+		info, ok := tenants[key]
+		if !ok {
+			info = TenantDebugInfo{Key: key, State: TenantStateNotStarted}
+		}
+		info.Exists = true
+		tenants[key] = info
+	}
+
+	c.pausedMu.Lock()
+	for key := range c.paused {
+		if info, ok := tenants[key]; ok {
+			info.State = TenantStatePaused
+			tenants[key] = info
+		}
+	}
+	c.pausedMu.Unlock()
+
+	c.syncErrorsMu.Lock()
+	for key, syncErr := range c.syncErrors {
+		if info, ok := tenants[key]; ok {
+			info.LastSyncError = syncErr.message
+			info.LastSyncErrorTime = &syncErr.time
+			tenants[key] = info
+		}
+	}
+	c.syncErrorsMu.Unlock()
+	return tenants
+}
+
+// DebugHandler returns an http.Handler that reports the tenants known to the
+// controller. It serves:
+//
+//   - GET /: a human-readable page listing the tenants.
+//   - GET /tenants: the tenants as a JSON array of TenantDebugInfo.
+//   - POST /tenants/requeue?key=<key>: requeues the tenant with the given key,
+//     if config.EnableActions is set.
+//
+// The paths are relative to where the handler is mounted, e.g. with
+// http.StripPrefix. The handler exposes tenant names and errors and does not
+// authenticate requests: it must only be mounted on a mux served on loopback
+// or behind authentication. Cross-origin browser requests to the actions are
+// rejected, see http.CrossOriginProtection, so that pages visited by an
+// operator cannot requeue tenants.
+func (c *Controller) DebugHandler(config DebugConfig) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", c.serveDebugPage(config))
+	mux.HandleFunc("GET /tenants", c.serveDebugTenants)
+	if config.EnableActions {
+		mux.Handle("POST /tenants/requeue", http.NewCrossOriginProtection().Handler(http.HandlerFunc(c.serveDebugRequeue)))
+	}
+	return mux
+}
+
+func (c *Controller) serveDebugTenants(w http.ResponseWriter, _ *http.Request) {
+	writeDebugJSON(w, http.StatusOK, c.Tenants())
+}
+
+func (c *Controller) serveDebugRequeue(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "missing key", http.StatusBadRequest)
+		return
+	}
+	known := false
+	for _, info := range c.Tenants() {
+		if info.Key == key {
+			known = true
+			break
+		}
+	}
+	if !known {
+		http.Error(w, "unknown tenant "+key, http.StatusNotFound)
+		return
+	}
+	klog.InfoS("Requeuing ProviderConfig on debug request", "key", key)
+	c.providerConfigQueue.EnqueueWithPriority(cache.ExplicitKey(key), taskqueue.PriorityNew)
+	writeDebugJSON(w, http.StatusAccepted, map[string]string{"requeued": key})
+}
+
+func writeDebugJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		klog.ErrorS(err, "Failed to write debug response")
+	}
+}
+
+var debugPageTemplate = template.Must(template.New("tenants").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Tenants</title></head>
+<body>
+<h1>Tenants ({{len .Tenants}})</h1>
+<table border="1" cellpadding="4">
+<tr><th>Key</th><th>Tenant UID</th><th>State</th><th>Controllers</th><th>Requeues</th><th>Last sync error</th>{{if .EnableActions}}<th></th>{{end}}</tr>
+{{range .Tenants}}<tr>
+<td>{{.Key}}{{if not .Exists}} (deleted){{end}}</td>
+<td>{{.TenantUID}}</td>
+<td>{{.State}}</td>
+<td>{{range .Starters}}{{.Name}}: {{if .Running}}running{{if .StartedAt}} since {{.StartedAt.Format "2006-01-02T15:04:05Z07:00"}}{{end}}{{if not .Ready}}, not ready{{end}}{{else}}stopped{{end}}{{if .Restarts}}, {{.Restarts}} restart(s){{end}}{{if .Panics}}, {{.Panics}} panic(s), last: {{.LastPanic}}{{end}}<br>{{end}}</td>
+<td>{{.Requeues}}</td>
+<td>{{if .LastSyncError}}{{.LastSyncError}}{{if .LastSyncErrorTime}} at {{.LastSyncErrorTime.Format "2006-01-02T15:04:05Z07:00"}}{{end}}{{end}}</td>
+{{if $.EnableActions}}<td><form method="post" action="tenants/requeue?key={{.Key}}"><button type="submit">Requeue</button></form></td>{{end}}
+</tr>{{end}}
+</table>
+</body>
+</html>
+`))
+
+func (c *Controller) serveDebugPage(config DebugConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		data := struct {
+			Tenants       []TenantDebugInfo
+			EnableActions bool
+		}{
+			Tenants:       c.Tenants(),
+			EnableActions: config.EnableActions,
+		}
+		if err := debugPageTemplate.Execute(w, data); err != nil {
+			klog.ErrorS(err, "Failed to render debug page")
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// selectiveFailingStarter fails to start the controllers of the
+// ProviderConfigs named in fail.
+type selectiveFailingStarter struct {
+	fail map[string]bool
+}
+
+func (s *selectiveFailingStarter) StartController(pc *unstructured.Unstructured) (chan<- struct{}, error) {
+	if s.fail[pc.GetName()] {
+		return nil, errors.New("injected start failure")
+	}
+	return make(chan struct{}), nil
+}
+
+// newDebugTestController returns a controller with a real manager that has
+// synced one running, one failing and one paused ProviderConfig.
+func newDebugTestController(t *testing.T, config DebugConfig) (*Controller, http.Handler) {
+	t.Helper()
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	starter := &selectiveFailingStarter{fail: map[string]bool{"pc-failing": true}}
+	ctrl := New(dynamicClient, &fakeInformer{Indexer: indexer, synced: true}, "test-finalizer", starter, make(chan struct{}))
+
+	paused := createTestProviderConfig("pc-paused")
+	paused.SetAnnotations(map[string]string{PausedAnnotation: "true"})
+	for _, pc := range []*unstructured.Unstructured{createTestProviderConfig("pc-running"), createTestProviderConfig("pc-failing"), paused} {
+		if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+			t.Fatalf("Failed to create ProviderConfig: %v", err)
+		}
+		if err := indexer.Add(pc); err != nil {
+			t.Fatalf("Failed to add ProviderConfig to indexer: %v", err)
+		}
+		_ = ctrl.syncWrapper(ctx, pc.GetName())
+	}
+	return ctrl, ctrl.DebugHandler(config)
+}
+
+func getDebugTenants(t *testing.T, handler http.Handler) map[string]TenantDebugInfo {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tenants", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /tenants returned %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var tenants []TenantDebugInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &tenants); err != nil {
+		t.Fatalf("Failed to decode tenants: %v", err)
+	}
+	byKey := map[string]TenantDebugInfo{}
+	for _, info := range tenants {
+		byKey[info.Key] = info
+	}
+	return byKey
+}
+
+// TestDebugHandlerTenants verifies that the debug handler reports the state,
+// controllers and last sync error of every tenant.
+func TestDebugHandlerTenants(t *testing.T) {
+	_, handler := newDebugTestController(t, DebugConfig{})
+	tenants := getDebugTenants(t, handler)
+	if len(tenants) != 3 {
+		t.Fatalf("Expected 3 tenants, got %v", tenants)
+	}
+
+	running := tenants["pc-running"]
+	if running.State != TenantStateRunning || !running.Exists {
+		t.Errorf("pc-running = %+v, want an existing running tenant", running)
+	}
+	if running.TenantUID != "pc-running" {
+		t.Errorf("pc-running has tenant UID %q, want %q", running.TenantUID, "pc-running")
+	}
+	if len(running.Starters) != 1 || running.Starters[0].Name != DefaultControllerStarterName || !running.Starters[0].Running || running.Starters[0].StartedAt == nil {
+		t.Errorf("pc-running has starters %+v, want the running default starter", running.Starters)
+	}
+	if running.LastSyncError != "" {
+		t.Errorf("pc-running has sync error %q, want none", running.LastSyncError)
+	}
+
+	failing := tenants["pc-failing"]
+	if failing.State != TenantStateNotStarted {
+		t.Errorf("pc-failing has state %s, want %s", failing.State, TenantStateNotStarted)
+	}
+	if !strings.Contains(failing.LastSyncError, "injected start failure") || failing.LastSyncErrorTime == nil {
+		t.Errorf("pc-failing has sync error %q at %v, want the start failure", failing.LastSyncError, failing.LastSyncErrorTime)
+	}
+
+	if paused := tenants["pc-paused"]; paused.State != TenantStatePaused {
+		t.Errorf("pc-paused has state %s, want %s", paused.State, TenantStatePaused)
+	}
+}
+
+// TestDebugHandlerClearsSyncError verifies that the last sync error is
+// forgotten once the tenant syncs successfully.
+func TestDebugHandlerClearsSyncError(t *testing.T) {
+	ctrl, handler := newDebugTestController(t, DebugConfig{})
+	ctrl.recordSyncResult("pc-failing", nil)
+	if got := getDebugTenants(t, handler)["pc-failing"].LastSyncError; got != "" {
+		t.Errorf("Expected the sync error to be cleared, got %q", got)
+	}
+}
+
+// TestDebugHandlerPage verifies that the human-readable page lists the tenants.
+func TestDebugHandlerPage(t *testing.T) {
+	_, handler := newDebugTestController(t, DebugConfig{EnableActions: true})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET / returned %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"pc-running", "pc-failing", "pc-paused", "injected start failure", "Requeue"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the page to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+// TestDebugHandlerPageWithoutStartTime verifies that the page renders
+// controllers that are tracked as running without a start time.
+func TestDebugHandlerPageWithoutStartTime(t *testing.T) {
+	ctrl, handler := newDebugTestController(t, DebugConfig{})
+	cs, _ := ctrl.manager.(*manager).controllers.GetOrCreate("pc-unstarted")
+	cs.controllersFor("extra").stopCh = make(chan struct{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET / returned %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"pc-unstarted", "extra: running, not ready", "</html>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the page to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+// TestDebugHandlerRequeue verifies that tenants can be requeued only when
+// actions are enabled, and only if they are known.
+func TestDebugHandlerRequeue(t *testing.T) {
+	post := func(handler http.Handler, target string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, nil))
+		return rec.Code
+	}
+
+	ctrl, readOnly := newDebugTestController(t, DebugConfig{})
+	if code := post(readOnly, "/tenants/requeue?key=pc-running"); code == http.StatusAccepted {
+		t.Error("Expected requeue to be rejected when actions are disabled")
+	}
+	if got := ctrl.providerConfigQueue.Len(); got != 0 {
+		t.Errorf("Expected an empty queue, got %d keys", got)
+	}
+
+	ctrl, handler := newDebugTestController(t, DebugConfig{EnableActions: true})
+	testCases := []struct {
+		target string
+		want   int
+	}{
+		{"/tenants/requeue", http.StatusBadRequest},
+		{"/tenants/requeue?key=pc-unknown", http.StatusNotFound},
+		{"/tenants/requeue?key=pc-running", http.StatusAccepted},
+	}
+	for _, tc := range testCases {
+		if got := post(handler, tc.target); got != tc.want {
+			t.Errorf("POST %s returned %d, want %d", tc.target, got, tc.want)
+		}
+	}
+	if got := ctrl.providerConfigQueue.Len(); got != 1 {
+		t.Errorf("Expected 1 requeued key, got %d", got)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/tenants/requeue?key=pc-failing", nil)
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Cross-site POST returned %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if got := ctrl.providerConfigQueue.Len(); got != 1 {
+		t.Errorf("Expected the cross-site request not to requeue, got %d keys", got)
+	}
+}
+
+// TestDebugHandlerDeletedTenant verifies that tenants whose object is gone but
+// whose controllers are still tracked are reported.
+func TestDebugHandlerDeletedTenant(t *testing.T) {
+	ctrl, handler := newDebugTestController(t, DebugConfig{})
+	obj, exists, err := ctrl.providerConfigLister.GetByKey("pc-running")
+	if err != nil || !exists {
+		t.Fatalf("Failed to get pc-running from the indexer: %v", err)
+	}
+	if err := ctrl.providerConfigLister.Delete(obj); err != nil {
+		t.Fatalf("Failed to delete pc-running from the indexer: %v", err)
+	}
+	info := getDebugTenants(t, handler)["pc-running"]
+	if info.Exists || info.State != TenantStateRunning {
+		t.Errorf("pc-running = %+v, want a running tenant that no longer exists", info)
+	}
+}
@@ -0,0 +1,80 @@
+package framework
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework/taskqueue"
+)
+
+// tenantMetricsInterval is how often the managed tenants metric is updated if
+// drift reconciliation is disabled.
+const tenantMetricsInterval = time.Minute
+
+// runDriftReconciler periodically compares the tracked tenants with the
+// ProviderConfigs in the lister, and updates the managed tenants metric, until
+// stopCh is closed. If the drift reconcile interval is not positive, it only
+// updates the metric, every tenantMetricsInterval.
+func (c *Controller) runDriftReconciler(stopCh <-chan struct{}) {
+	interval, tick := c.driftReconcileInterval, c.reconcileDrift
+	if interval <= 0 {
+		interval, tick = tenantMetricsInterval, c.updateTenantMetrics
+	}
+	ticker := c.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C():
+			tick()
+		}
+	}
+}
+
+// reconcileDrift enqueues the ProviderConfigs whose tenant controllers do not
+// match the lister: live ProviderConfigs without controllers, controllers whose
+// ProviderConfig is gone, and running controllers whose ProviderConfig lost its
+// finalizer. The sync of each key makes the correction.
+func (c *Controller) reconcileDrift() {
+	tracked := c.manager.TrackedProviderConfigs()
+	pending := c.manager.PendingProviderConfigs()
+	for _, obj := range c.providerConfigLister.List() {
+		pc, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		key := c.tenants.key(pc)
+		running, isTracked := tracked[key]
+		delete(tracked, key)
+		if !pc.GetDeletionTimestamp().IsZero() || isPaused(pc) || (c.sharder != nil && !c.sharder.owns(key)) {
+			// Terminating, paused and unowned ProviderConfigs are handled by their own syncs.
+			continue
+		}
+		switch {
+		case !isTracked:
+			if c.providerConfigQueue.NumRequeues(cache.ExplicitKey(key)) > 0 || pending.Has(key) {
+				// A failed or pending start is already being retried.
+				continue
+			}
+			c.correctDrift(key, driftMissingTenant, taskqueue.PriorityNew)
+		case running && !c.manager.HasFinalizer(pc):
+			c.correctDrift(key, driftMissingFinalizer, taskqueue.PriorityNew)
+		}
+	}
+	for key := range tracked {
+		c.correctDrift(key, driftOrphanedTenant, taskqueue.PriorityTerminating)
+	}
+	c.updateTenantMetrics()
+}
+
+// correctDrift records the drift and enqueues key with the given priority so
+// that its sync corrects it.
+func (c *Controller) correctDrift(key, kind string, priority taskqueue.Priority) {
+	klog.InfoS("Detected drift between tenant controllers and ProviderConfigs", "key", key, "kind", kind)
+	c.metrics.driftCorrections.WithLabelValues(kind).Inc()
+	c.providerConfigQueue.EnqueueWithPriority(cache.ExplicitKey(key), priority)
+}
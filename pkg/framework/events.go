@@ -0,0 +1,85 @@
+package framework
+
+import (
+	"errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Event reasons recorded on ProviderConfigs in addition to the condition reasons.
+const (
+	// ReasonFinalizerAdded indicates that the framework finalizer was added.
+	ReasonFinalizerAdded = "FinalizerAdded"
+	// ReasonFinalizerRemoved indicates that the framework finalizer was removed.
+	ReasonFinalizerRemoved = "FinalizerRemoved"
+)
+
+const (
+	// defaultEventQPS and defaultEventBurst match the defaults of the client-go
+	// event correlator: a burst of 25 Events per ProviderConfig, refilled at
+	// one Event every 5 minutes.
+	defaultEventQPS   = 1. / 300.
+	defaultEventBurst = 25
+)
+
+// EventConfig configures the Kubernetes Events recorded on ProviderConfigs for
+// lifecycle transitions.
+type EventConfig struct {
+	// Client creates the Events.
+	Client typedcorev1.EventsGetter
+	// Component is the source of the Events. It defaults to
+	// "provider-config-controller".
+	Component string
+	// QPS and Burst configure the token bucket of the event correlator, which
+	// drops the Events of a ProviderConfig that records them faster. They
+	// default to one Event every 5 minutes and a burst of 25.
+	QPS   float32
+	Burst int
+}
+
+// WithEvents records Normal and Warning Events on ProviderConfigs when their
+// finalizer is added or removed and when their controllers start, fail to
+// start or stop. Events go through an event correlator that aggregates
+// similar Events and rate limits them per ProviderConfig, so that a flapping
+// tenant cannot spam the cluster. By default, no Events are recorded.
+func WithEvents(config EventConfig) Option {
+	return func(o *options) {
+		if config.Client == nil {
+			o.errs = append(o.errs, errors.New("events require a client"))
+			return
+		}
+		if config.Component == "" {
+			config.Component = providerConfigControllerName
+		}
+		if config.QPS == 0 {
+			config.QPS = defaultEventQPS
+		}
+		if config.Burst == 0 {
+			config.Burst = defaultEventBurst
+		}
+		o.events = &config
+	}
+}
+
+// newEventRecorder returns an EventRecorder writing Events through the
+// client of config, and the broadcaster to shut down once done.
+func newEventRecorder(config EventConfig) (record.EventRecorder, record.EventBroadcaster) {
+	broadcaster := record.NewBroadcaster(record.WithCorrelatorOptions(record.CorrelatorOptions{
+		QPS:       config.QPS,
+		BurstSize: config.Burst,
+	}))
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: config.Client.Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: config.Component}), broadcaster
+}
+
+// recordEvent records an Event on pc if Events are enabled.
+func (m *manager) recordEvent(pc *unstructured.Unstructured, eventType, reason, messageFmt string, args ...any) {
+	if m.recorder == nil {
+		return
+	}
+	m.recorder.Eventf(pc, eventType, reason, messageFmt, args...)
+}
@@ -0,0 +1,132 @@
+package framework
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+// drainEvents returns the Events recorded by recorder so far.
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case e := <-recorder.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+// TestManagerRecordsLifecycleEvents verifies that the manager records Events
+// for finalizer changes and for starting and stopping controllers.
+func TestManagerRecordsLifecycleEvents(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	recorder := record.NewFakeRecorder(100)
+	manager := newManager(dynamicClient, "test-finalizer", newMockControllerStarter())
+	manager.recorder = recorder
+
+	pc := createTestProviderConfig("pc-events")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	want := []string{
+		"Normal FinalizerAdded Added finalizer test-finalizer",
+		"Normal ControllersStarted Started controllers " + DefaultControllerStarterName,
+	}
+	if got := drainEvents(recorder); !slices.Equal(got, want) {
+		t.Errorf("Events after start = %q, want %q", got, want)
+	}
+
+	if err := manager.StopControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	want = []string{
+		"Normal ControllersStopped Controllers for the ProviderConfig have been stopped",
+		"Normal FinalizerRemoved Removed finalizer test-finalizer",
+	}
+	if got := drainEvents(recorder); !slices.Equal(got, want) {
+		t.Errorf("Events after stop = %q, want %q", got, want)
+	}
+}
+
+// TestManagerRecordsStartFailureEvent verifies that a failed start records a
+// Warning Event with the error.
+func TestManagerRecordsStartFailureEvent(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	recorder := record.NewFakeRecorder(100)
+	starter := newMockControllerStarter()
+	starter.shouldFailStart = true
+	manager := newManager(dynamicClient, "test-finalizer", starter)
+	manager.recorder = recorder
+
+	pc := createTestProviderConfig("pc-events")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err == nil {
+		t.Fatalf("Expected start to fail")
+	}
+
+	events := drainEvents(recorder)
+	if !slices.ContainsFunc(events, func(e string) bool {
+		return strings.HasPrefix(e, corev1.EventTypeWarning+" "+ReasonControllerStartFailed+" ")
+	}) {
+		t.Errorf("Expected a Warning %s Event, got %q", ReasonControllerStartFailed, events)
+	}
+	if !slices.Contains(events, "Normal FinalizerRemoved Removed finalizer test-finalizer after the controllers failed to start") {
+		t.Errorf("Expected an Event for the finalizer rollback, got %q", events)
+	}
+}
+
+// TestEventRecorderRateLimitsPerProviderConfig verifies that the Events of a
+// ProviderConfig that records them faster than the correlator allows are
+// dropped.
+func TestEventRecorderRateLimitsPerProviderConfig(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	recorder, broadcaster := newEventRecorder(EventConfig{
+		Client:    client.CoreV1(),
+		Component: "test",
+		QPS:       0.001,
+		Burst:     2,
+	})
+	defer broadcaster.Shutdown()
+
+	pc := createTestProviderConfig("pc-flapping")
+	for i := 0; i < 5; i++ {
+		recorder.Eventf(pc, corev1.EventTypeWarning, ReasonControllerStartFailed, "attempt %d failed", i)
+	}
+
+	countEvents := func() int {
+		events, err := client.CoreV1().Events(metav1.NamespaceDefault).List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("Failed to list Events: %v", err)
+		}
+		return len(events.Items)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return countEvents() == 2, nil
+	}); err != nil {
+		t.Fatalf("Expected 2 Events to be written, got %d", countEvents())
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := countEvents(); got != 2 {
+		t.Errorf("Expected the correlator to drop Events over the burst, got %d Events", got)
+	}
+}
@@ -0,0 +1,96 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// finalizerPatch returns a JSON merge patch that sets the finalizers of obj.
+// The patch carries the resourceVersion of obj as a precondition, so the API
+// server rejects it with a conflict if obj changed since it was read.
+func finalizerPatch(obj *unstructured.Unstructured, finalizers []string) ([]byte, error) {
+	if finalizers == nil {
+		finalizers = []string{}
+	}
+	return json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"finalizers":      finalizers,
+			"resourceVersion": obj.GetResourceVersion(),
+		},
+	})
+}
+
+// patchFinalizers applies mutate to the finalizers of the ProviderConfig and
+// writes the result with a merge patch. The first attempt uses current, which
+// may be a stale informer copy; nil fetches the ProviderConfig first. On a
+// conflict, the latest ProviderConfig is fetched and mutate is applied again.
+// mutate returns false if the finalizers need no change. It returns whether
+// the finalizers were patched.
+func (m *manager) patchFinalizers(ctx context.Context, pc, current *unstructured.Unstructured, mutate func([]string) ([]string, bool)) (bool, error) {
+	patched := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if current == nil {
+			latest, err := m.getProviderConfig(ctx, pc)
+			if err != nil {
+				return err
+			}
+			current = latest
+		}
+		finalizers, ok := mutate(current.GetFinalizers())
+		if !ok {
+			return nil
+		}
+		patch, err := finalizerPatch(current, finalizers)
+		if err != nil {
+			return err
+		}
+		if _, err := m.tenants.resource(m.client, current).Patch(ctx, current.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			current = nil
+			return err
+		}
+		patched = true
+		return nil
+	})
+	return patched, err
+}
+
+// ensureFinalizer adds the finalizer of the manager to the ProviderConfig
+// unless it already carries it. pc is used for the first attempt.
+func (m *manager) ensureFinalizer(ctx context.Context, pc *unstructured.Unstructured) (bool, error) {
+	added, err := m.patchFinalizers(ctx, pc, pc, func(finalizers []string) ([]string, bool) {
+		if slices.Contains(finalizers, m.finalizerName) {
+			return nil, false
+		}
+		return append(finalizers, m.finalizerName), true
+	})
+	if err != nil {
+		m.metrics.finalizerErrors.WithLabelValues(finalizerOperationAdd).Inc()
+	}
+	return added, err
+}
+
+// removeFinalizer removes the finalizer of the manager from the latest copy of
+// the ProviderConfig. A ProviderConfig that no longer exists has no finalizer
+// to remove.
+func (m *manager) removeFinalizer(ctx context.Context, pc *unstructured.Unstructured) (bool, error) {
+	removed, err := m.patchFinalizers(ctx, pc, nil, func(finalizers []string) ([]string, bool) {
+		if !slices.Contains(finalizers, m.finalizerName) {
+			return nil, false
+		}
+		return slices.DeleteFunc(finalizers, func(f string) bool { return f == m.finalizerName }), true
+	})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		m.metrics.finalizerErrors.WithLabelValues(finalizerOperationRemove).Inc()
+	}
+	return removed, err
+}
@@ -0,0 +1,227 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// patchConflicts makes the first n patches of ProviderConfigs fail with a
+// conflict and records the bodies of all patches.
+type patchConflicts struct {
+	mu        sync.Mutex
+	conflicts int
+	patches   []map[string]any
+}
+
+func injectPatchConflicts(client *fake.FakeDynamicClient, n int) *patchConflicts {
+	p := &patchConflicts{conflicts: n}
+	client.PrependReactor("patch", testProviderConfigGVR.Resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(k8stesting.PatchAction)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		var patch map[string]any
+		if err := json.Unmarshal(patchAction.GetPatch(), &patch); err != nil {
+			return true, nil, err
+		}
+		if patchAction.GetPatchType() != types.MergePatchType {
+			return true, nil, errors.New("expected a merge patch")
+		}
+		p.patches = append(p.patches, patch)
+		if p.conflicts != 0 {
+			p.conflicts--
+			return true, nil, apierrors.NewConflict(testProviderConfigGVR.GroupResource(), patchAction.GetName(), errors.New("object was modified"))
+		}
+		return false, nil, nil
+	})
+	return p
+}
+
+func (p *patchConflicts) get() []map[string]any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]map[string]any(nil), p.patches...)
+}
+
+func patchResourceVersion(patch map[string]any) string {
+	metadata, _ := patch["metadata"].(map[string]any)
+	rv, _ := metadata["resourceVersion"].(string)
+	return rv
+}
+
+// TestManagerStartAddsFinalizerOnConflict verifies that a conflict while adding
+// the finalizer to a stale ProviderConfig is retried with the latest copy
+// instead of failing the start.
+func TestManagerStartAddsFinalizerOnConflict(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newMockControllerStarter()
+	manager := newManager(dynamicClient, "test-finalizer", starter)
+
+	pc := createTestProviderConfig("pc-conflict")
+	pc.SetResourceVersion("1")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	// A concurrent writer updates the ProviderConfig after the informer saw it.
+	latest, err := providerConfigFromClient(ctx, dynamicClient, "pc-conflict")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	latest.SetLabels(map[string]string{"team": "a"})
+	latest.SetResourceVersion("2")
+	if _, err := dynamicClient.Resource(testProviderConfigGVR).Update(ctx, latest, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update ProviderConfig: %v", err)
+	}
+	conflicts := injectPatchConflicts(dynamicClient, 1)
+
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if got := starter.getStartCallCount(); got != 1 {
+		t.Errorf("Expected 1 start call, got %d", got)
+	}
+
+	patches := conflicts.get()
+	if len(patches) != 2 {
+		t.Fatalf("Expected 2 finalizer patches, got %d", len(patches))
+	}
+	if got := patchResourceVersion(patches[0]); got != "1" {
+		t.Errorf("First patch has resourceVersion %q, want the stale %q", got, "1")
+	}
+	if got := patchResourceVersion(patches[1]); got != "2" {
+		t.Errorf("Retried patch has resourceVersion %q, want the latest %q", got, "2")
+	}
+
+	updated, err := providerConfigFromClient(ctx, dynamicClient, "pc-conflict")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if !hasFinalizer(updated, "test-finalizer") {
+		t.Errorf("Expected finalizer to be added, got %v", updated.GetFinalizers())
+	}
+	if updated.GetLabels()["team"] != "a" {
+		t.Errorf("Expected the concurrent label update to be preserved, got %v", updated.GetLabels())
+	}
+}
+
+// TestManagerStartFailsOnPersistentConflict verifies that the start fails with
+// the conflict once the retries are exhausted, without starting controllers.
+func TestManagerStartFailsOnPersistentConflict(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newMockControllerStarter()
+	manager := newManager(dynamicClient, "test-finalizer", starter)
+
+	pc := createTestProviderConfig("pc-always-conflict")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	injectPatchConflicts(dynamicClient, -1)
+
+	err := manager.StartControllersForProviderConfig(ctx, pc)
+	if !apierrors.IsConflict(err) {
+		t.Fatalf("Expected a conflict error, got %v", err)
+	}
+	if got := starter.getStartCallCount(); got != 0 {
+		t.Errorf("Expected no start calls, got %d", got)
+	}
+	if _, ok := manager.controllers.Get("pc-always-conflict"); ok {
+		t.Error("Expected no ControllerMap entry after the failed start")
+	}
+}
+
+// TestManagerStopRemovesFinalizerOnConflict verifies that finalizer removal
+// is retried on conflicts and keeps the finalizers of other owners.
+func TestManagerStopRemovesFinalizerOnConflict(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newMockControllerStarter()
+	manager := newManager(dynamicClient, "test-finalizer", starter)
+
+	pc := createTestProviderConfig("pc-stop-conflict")
+	pc.SetFinalizers([]string{"other-finalizer"})
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	conflicts := injectPatchConflicts(dynamicClient, 2)
+
+	latest, err := providerConfigFromClient(ctx, dynamicClient, "pc-stop-conflict")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	latest.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	if err := manager.StopControllersForProviderConfig(ctx, latest); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if got := len(conflicts.get()); got != 3 {
+		t.Errorf("Expected 3 finalizer patches, got %d", got)
+	}
+
+	updated, err := providerConfigFromClient(ctx, dynamicClient, "pc-stop-conflict")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if hasFinalizer(updated, "test-finalizer") {
+		t.Error("Expected finalizer to be removed")
+	}
+	if !hasFinalizer(updated, "other-finalizer") {
+		t.Errorf("Expected other finalizers to be kept, got %v", updated.GetFinalizers())
+	}
+}
+
+// TestManagerStartFailureRollsBackFinalizerOnConflict verifies that the
+// finalizer rollback after a start failure is retried on conflicts.
+func TestManagerStartFailureRollsBackFinalizerOnConflict(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newMockControllerStarter()
+	starter.shouldFailStart = true
+	manager := newManager(dynamicClient, "test-finalizer", starter)
+
+	pc := createTestProviderConfig("pc-rollback-conflict")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	// Let the finalizer be added, then fail the first rollback attempt.
+	var mu sync.Mutex
+	patches := 0
+	dynamicClient.PrependReactor("patch", testProviderConfigGVR.Resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		patches++
+		if patches == 2 {
+			return true, nil, apierrors.NewConflict(testProviderConfigGVR.GroupResource(), "pc-rollback-conflict", errors.New("object was modified"))
+		}
+		return false, nil, nil
+	})
+
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err == nil {
+		t.Fatal("Expected start to fail")
+	}
+	mu.Lock()
+	if patches != 3 {
+		t.Errorf("Expected 3 finalizer patches, got %d", patches)
+	}
+	mu.Unlock()
+	updated, err := providerConfigFromClient(ctx, dynamicClient, "pc-rollback-conflict")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if hasFinalizer(updated, "test-finalizer") {
+		t.Errorf("Expected finalizer to be rolled back, got %v", updated.GetFinalizers())
+	}
+}
@@ -0,0 +1,299 @@
+package frameworktest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework"
+)
+
+const (
+	// DefaultFinalizer is the finalizer the Harness passes to framework.NewValidated.
+	DefaultFinalizer = "frameworktest.gke.io/finalizer"
+	// DefaultTimeout is how long the assertions of a Harness wait for the
+	// expected state by default.
+	DefaultTimeout = 10 * time.Second
+)
+
+// pollInterval is how often the assertions of a Harness check the state.
+const pollInterval = 10 * time.Millisecond
+
+// Harness runs a framework.Controller against a fake dynamic client and an
+// informer on it, so that tests drive the Controller by creating, updating
+// and deleting ProviderConfigs, like an API server would. ProviderConfigs are
+// of ProviderConfigGVR.
+//
+// Unlike an API server, the fake client keeps a ProviderConfig that is being
+// deleted after its finalizers are removed; use ForceDelete to remove it.
+type Harness struct {
+	// Client is the fake dynamic client the Controller works with.
+	Client *fake.FakeDynamicClient
+	// Controller is the Controller under test.
+	Controller *framework.Controller
+	// FinalizerName is the finalizer the Controller adds to ProviderConfigs.
+	FinalizerName string
+	// Timeout bounds how long assertions wait for the expected state.
+	Timeout time.Duration
+
+	t        testing.TB
+	informer cache.SharedIndexInformer
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	started  bool
+	done     chan struct{}
+}
+
+// NewHarness returns a Harness for a Controller that starts controllers with
+// starter, e.g. a RecordingStarter, and is configured with opts. The test fails
+// if opts are invalid. The Controller runs once Start is called.
+func NewHarness(t testing.TB, starter framework.ControllerStarter, opts ...framework.Option) *Harness {
+	t.Helper()
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		ProviderConfigGVR: "ProviderConfigList",
+	})
+	informer := dynamicinformer.NewFilteredDynamicInformer(client, ProviderConfigGVR, metav1.NamespaceAll, 0, cache.Indexers{}, nil).Informer()
+	stopCh := make(chan struct{})
+	controller, err := framework.NewValidated(client, informer, DefaultFinalizer, starter, stopCh, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create Controller: %v", err)
+	}
+	return &Harness{
+		Client:        client,
+		Controller:    controller,
+		FinalizerName: DefaultFinalizer,
+		Timeout:       DefaultTimeout,
+		t:             t,
+		informer:      informer,
+		stopCh:        stopCh,
+		done:          make(chan struct{}),
+	}
+}
+
+// Start runs the informer and the Controller. They are stopped by Stop, which
+// is also called when the test finishes.
+func (h *Harness) Start() {
+	h.t.Helper()
+	if h.started {
+		h.t.Fatal("Harness was already started")
+	}
+	h.started = true
+	go h.informer.Run(h.stopCh)
+	go func() {
+		defer close(h.done)
+		h.Controller.Run()
+	}()
+	h.t.Cleanup(h.Stop)
+}
+
+// Stop stops the Controller and waits until Run returned, i.e. until the
+// controllers of all tenants were stopped, see framework.WithShutdownTimeout.
+func (h *Harness) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stopCh)
+		if h.started {
+			<-h.done
+		}
+	})
+}
+
+// Create creates pc through the fake client and returns the created object.
+func (h *Harness) Create(pc *unstructured.Unstructured) *unstructured.Unstructured {
+	h.t.Helper()
+	created, err := h.Client.Resource(ProviderConfigGVR).Create(context.Background(), pc, metav1.CreateOptions{})
+	if err != nil {
+		h.t.Fatalf("Failed to create ProviderConfig %s: %v", pc.GetName(), err)
+	}
+	return created
+}
+
+// Get returns the ProviderConfig with the given name, or nil if it does not exist.
+func (h *Harness) Get(name string) *unstructured.Unstructured {
+	h.t.Helper()
+	pc, err := h.Client.Resource(ProviderConfigGVR).Get(context.Background(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		h.t.Fatalf("Failed to get ProviderConfig %s: %v", name, err)
+	}
+	return pc
+}
+
+// Update applies mutate to the latest copy of the ProviderConfig with the
+// given name and writes it back, retrying on conflicts with the Controller.
+func (h *Harness) Update(name string, mutate func(pc *unstructured.Unstructured)) *unstructured.Unstructured {
+	h.t.Helper()
+	var updated *unstructured.Unstructured
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pc, err := h.Client.Resource(ProviderConfigGVR).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		mutate(pc)
+		updated, err = h.Client.Resource(ProviderConfigGVR).Update(context.Background(), pc, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		h.t.Fatalf("Failed to update ProviderConfig %s: %v", name, err)
+	}
+	return updated
+}
+
+// Delete deletes the ProviderConfig with the given name like an API server:
+// if it has finalizers, it only gets a deletion timestamp.
+func (h *Harness) Delete(name string) {
+	h.t.Helper()
+	pc := h.Get(name)
+	if pc == nil {
+		h.t.Fatalf("Failed to delete ProviderConfig %s: not found", name)
+	}
+	if len(pc.GetFinalizers()) == 0 {
+		h.ForceDelete(name)
+		return
+	}
+	h.Update(name, func(pc *unstructured.Unstructured) {
+		now := metav1.Now()
+		pc.SetDeletionTimestamp(&now)
+	})
+}
+
+// ForceDelete removes the ProviderConfig with the given name regardless of
+// its finalizers.
+func (h *Harness) ForceDelete(name string) {
+	h.t.Helper()
+	if err := h.Client.Resource(ProviderConfigGVR).Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		h.t.Fatalf("Failed to delete ProviderConfig %s: %v", name, err)
+	}
+}
+
+// Eventually waits until condition returns true and fails the test with
+// description if it does not within the Timeout of the Harness.
+func (h *Harness) Eventually(description string, condition func() bool) {
+	h.t.Helper()
+	if err := wait.PollUntilContextTimeout(context.Background(), pollInterval, h.Timeout, true, func(context.Context) (bool, error) {
+		return condition(), nil
+	}); err != nil {
+		h.t.Fatalf("Timed out after %v waiting for %s", h.Timeout, description)
+	}
+}
+
+// Tenant returns what the Controller reports about the tenant with the given
+// key, and whether the Controller knows the tenant.
+func (h *Harness) Tenant(key string) (framework.TenantDebugInfo, bool) {
+	tenants := h.Controller.Tenants()
+	i := slices.IndexFunc(tenants, func(info framework.TenantDebugInfo) bool { return info.Key == key })
+	if i < 0 {
+		return framework.TenantDebugInfo{}, false
+	}
+	return tenants[i], true
+}
+
+// running reports whether controllers of any starter run for the tenant with
+// the given key.
+func (h *Harness) running(key string) bool {
+	info, _ := h.Tenant(key)
+	return slices.ContainsFunc(info.Starters, func(s framework.StarterDebugInfo) bool { return s.Running })
+}
+
+// AssertControllersStarted waits until controllers run for the ProviderConfig
+// with the given key.
+func (h *Harness) AssertControllersStarted(key string) {
+	h.t.Helper()
+	h.Eventually(fmt.Sprintf("controllers to start for %s", key), func() bool { return h.running(key) })
+}
+
+// AssertControllersStopped waits until no controllers run for the
+// ProviderConfig with the given key.
+func (h *Harness) AssertControllersStopped(key string) {
+	h.t.Helper()
+	h.Eventually(fmt.Sprintf("controllers to stop for %s", key), func() bool { return !h.running(key) })
+}
+
+// AssertTenantState waits until the Controller reports the tenant with the
+// given key in state.
+func (h *Harness) AssertTenantState(key string, state framework.TenantState) {
+	h.t.Helper()
+	h.Eventually(fmt.Sprintf("tenant %s to be %s", key, state), func() bool {
+		info, ok := h.Tenant(key)
+		return ok && info.State == state
+	})
+}
+
+// AssertFinalizerAdded waits until the ProviderConfig with the given name
+// carries the finalizer of the Controller.
+func (h *Harness) AssertFinalizerAdded(name string) {
+	h.t.Helper()
+	h.Eventually(fmt.Sprintf("finalizer %s to be added to %s", h.FinalizerName, name), func() bool {
+		pc := h.Get(name)
+		return pc != nil && slices.Contains(pc.GetFinalizers(), h.FinalizerName)
+	})
+}
+
+// AssertFinalizerRemoved waits until the ProviderConfig with the given name no
+// longer carries the finalizer of the Controller, or no longer exists.
+func (h *Harness) AssertFinalizerRemoved(name string) {
+	h.t.Helper()
+	h.Eventually(fmt.Sprintf("finalizer %s to be removed from %s", h.FinalizerName, name), func() bool {
+		pc := h.Get(name)
+		return pc == nil || !slices.Contains(pc.GetFinalizers(), h.FinalizerName)
+	})
+}
+
+// AssertCondition waits until the status of the ProviderConfig with the given
+// name has a condition of conditionType with status, and returns it.
+func (h *Harness) AssertCondition(name, conditionType string, status metav1.ConditionStatus) metav1.Condition {
+	h.t.Helper()
+	var found metav1.Condition
+	h.Eventually(fmt.Sprintf("condition %s=%s on %s", conditionType, status, name), func() bool {
+		condition := h.Condition(name, conditionType)
+		if condition == nil || condition.Status != status {
+			return false
+		}
+		found = *condition
+		return true
+	})
+	return found
+}
+
+// Condition returns the status condition of conditionType of the
+// ProviderConfig with the given name, or nil if it has none.
+func (h *Harness) Condition(name, conditionType string) *metav1.Condition {
+	h.t.Helper()
+	pc := h.Get(name)
+	if pc == nil {
+		return nil
+	}
+	raw, _, err := unstructured.NestedSlice(pc.Object, "status", "conditions")
+	if err != nil {
+		h.t.Fatalf("Failed to read status conditions of ProviderConfig %s: %v", name, err)
+	}
+	conditions := make([]metav1.Condition, 0, len(raw))
+	for _, r := range raw {
+		m, ok := r.(map[string]any)
+		if !ok {
+			h.t.Fatalf("Expected status condition of ProviderConfig %s to be an object, got %T", name, r)
+		}
+		var c metav1.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &c); err != nil {
+			h.t.Fatalf("Failed to convert status condition of ProviderConfig %s: %v", name, err)
+		}
+		conditions = append(conditions, c)
+	}
+	return meta.FindStatusCondition(conditions, conditionType)
+}
@@ -0,0 +1,117 @@
+package frameworktest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework"
+)
+
+// TestHarnessLifecycle verifies that the Harness drives a tenant through
+// start and deletion.
+func TestHarnessLifecycle(t *testing.T) {
+	starter := NewRecordingStarter()
+	h := NewHarness(t, starter)
+	h.Start()
+
+	h.Create(NewProviderConfig("pc-1").Build())
+	h.AssertControllersStarted("pc-1")
+	h.AssertFinalizerAdded("pc-1")
+	h.AssertTenantState("pc-1", framework.TenantStateRunning)
+	h.AssertCondition("pc-1", framework.ConditionControllersRunning, metav1.ConditionTrue)
+	if !starter.Running("pc-1") {
+		t.Error("Expected the starter to report running controllers")
+	}
+
+	h.Delete("pc-1")
+	h.AssertControllersStopped("pc-1")
+	h.AssertFinalizerRemoved("pc-1")
+	if got := starter.Stops("pc-1"); got != 1 {
+		t.Errorf("Expected 1 stop, got %d", got)
+	}
+	if got := starter.Starts("pc-1"); got != 1 {
+		t.Errorf("Expected 1 start, got %d", got)
+	}
+}
+
+// TestHarnessFaults verifies that the faults injected by a RecordingStarter
+// are observed by the Controller.
+func TestHarnessFaults(t *testing.T) {
+	starter := NewRecordingStarter()
+	h := NewHarness(t, starter, framework.WithRestartBackoff(time.Millisecond, 10*time.Millisecond))
+	h.Start()
+
+	starter.FailStarts("pc-failing", errors.New("injected start failure"))
+	h.Create(NewProviderConfig("pc-failing").Build())
+	h.AssertCondition("pc-failing", framework.ConditionStartFailed, metav1.ConditionTrue)
+	starter.FailStarts("pc-failing", nil)
+	h.AssertControllersStarted("pc-failing")
+
+	h.Create(NewProviderConfig("pc-crashing").Build())
+	h.AssertControllersStarted("pc-crashing")
+	if !starter.Crash("pc-crashing") {
+		t.Fatal("Expected controllers to crash")
+	}
+	h.Eventually("controllers to restart after the crash", func() bool { return starter.Starts("pc-crashing") == 2 })
+	h.AssertControllersStarted("pc-crashing")
+
+	if !starter.Panic("pc-crashing", "injected panic") {
+		t.Fatal("Expected controllers to panic")
+	}
+	h.Eventually("controllers to restart after the panic", func() bool { return starter.Starts("pc-crashing") == 3 })
+	h.AssertCondition("pc-crashing", framework.ConditionDegraded, metav1.ConditionFalse)
+
+	starter.SetReady("pc-starting", false)
+	h.Create(NewProviderConfig("pc-starting").Build())
+	h.AssertTenantState("pc-starting", framework.TenantStateStarting)
+	starter.SetReady("pc-starting", true)
+	h.AssertTenantState("pc-starting", framework.TenantStateRunning)
+
+	// The faults of one tenant do not restart the controllers of the others.
+	if got := starter.Starts("pc-failing"); got != 1 {
+		t.Errorf("Expected 1 start of pc-failing, got %d", got)
+	}
+}
+
+// TestHarnessUpdate verifies that updates through the Harness reach the
+// Controller.
+func TestHarnessUpdate(t *testing.T) {
+	starter := NewRecordingStarter()
+	h := NewHarness(t, starter)
+	h.Start()
+
+	h.Create(NewProviderConfig("pc-paused").Build())
+	h.AssertControllersStarted("pc-paused")
+	h.Update("pc-paused", func(pc *unstructured.Unstructured) {
+		pc.SetAnnotations(map[string]string{framework.PausedAnnotation: "true"})
+	})
+	h.AssertTenantState("pc-paused", framework.TenantStatePaused)
+	h.AssertControllersStopped("pc-paused")
+	h.AssertCondition("pc-paused", framework.ConditionPaused, metav1.ConditionTrue)
+}
+
+// TestHarnessStop verifies that stopping the Harness stops the controllers of
+// every tenant but keeps their finalizers.
+func TestHarnessStop(t *testing.T) {
+	starter := NewRecordingStarter()
+	h := NewHarness(t, starter)
+	h.Start()
+
+	for _, name := range []string{"pc-1", "pc-2"} {
+		h.Create(NewProviderConfig(name).Build())
+		h.AssertControllersStarted(name)
+	}
+	h.Stop()
+	for _, name := range []string{"pc-1", "pc-2"} {
+		if starter.Running(name) {
+			t.Errorf("Expected controllers for %s to be stopped", name)
+		}
+		if pc := h.Get(name); pc == nil || len(pc.GetFinalizers()) == 0 {
+			t.Errorf("Expected %s to keep its finalizer, got %v", name, pc)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package frameworktest provides fakes and helpers for testing
+// ControllerStarters against the framework: builders for ProviderConfig
+// objects, a recording starter that injects faults, and a Harness that runs a
+// framework.Controller against a fake dynamic client.
+package frameworktest
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework"
+)
+
+var (
+	// ProviderConfigGVR is the resource of the ProviderConfigs built by
+	// NewProviderConfig and watched by the Harness.
+	ProviderConfigGVR = schema.GroupVersionResource{Group: "cloud.gke.io", Version: "v1", Resource: "providerconfigs"}
+	// ProviderConfigGVK is the kind of the ProviderConfigs built by NewProviderConfig.
+	ProviderConfigGVK = schema.GroupVersionKind{Group: "cloud.gke.io", Version: "v1", Kind: "ProviderConfig"}
+)
+
+// ProviderConfigBuilder builds ProviderConfig objects for tests.
+type ProviderConfigBuilder struct {
+	obj *unstructured.Unstructured
+}
+
+// NewProviderConfig returns a builder for a ProviderConfig with the given name
+// and a spec with a test project ID.
+func NewProviderConfig(name string) *ProviderConfigBuilder {
+	obj := &unstructured.Unstructured{
+		Object: map[string]any{
+			"spec": map[string]any{
+				"projectID": "test-project",
+			},
+		},
+	}
+	obj.SetGroupVersionKind(ProviderConfigGVK)
+	obj.SetName(name)
+	return &ProviderConfigBuilder{obj: obj}
+}
+
+// WithUID sets the UID of the ProviderConfig.
+func (b *ProviderConfigBuilder) WithUID(uid types.UID) *ProviderConfigBuilder {
+	b.obj.SetUID(uid)
+	return b
+}
+
+// WithLabels adds labels to the ProviderConfig.
+func (b *ProviderConfigBuilder) WithLabels(labels map[string]string) *ProviderConfigBuilder {
+	b.obj.SetLabels(merge(b.obj.GetLabels(), labels))
+	return b
+}
+
+// WithAnnotations adds annotations to the ProviderConfig.
+func (b *ProviderConfigBuilder) WithAnnotations(annotations map[string]string) *ProviderConfigBuilder {
+	b.obj.SetAnnotations(merge(b.obj.GetAnnotations(), annotations))
+	return b
+}
+
+// Paused annotates the ProviderConfig with framework.PausedAnnotation.
+func (b *ProviderConfigBuilder) Paused() *ProviderConfigBuilder {
+	return b.WithAnnotations(map[string]string{framework.PausedAnnotation: "true"})
+}
+
+// WithSpecField sets the spec field at the given path, e.g. "projectID", to
+// value. value must be a JSON-compatible value such as a string, int64 or map.
+func (b *ProviderConfigBuilder) WithSpecField(value any, fields ...string) *ProviderConfigBuilder {
+	if err := unstructured.SetNestedField(b.obj.Object, value, append([]string{"spec"}, fields...)...); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// WithGeneration sets the generation of the ProviderConfig.
+func (b *ProviderConfigBuilder) WithGeneration(generation int64) *ProviderConfigBuilder {
+	b.obj.SetGeneration(generation)
+	return b
+}
+
+// WithFinalizers adds finalizers to the ProviderConfig.
+func (b *ProviderConfigBuilder) WithFinalizers(finalizers ...string) *ProviderConfigBuilder {
+	b.obj.SetFinalizers(append(b.obj.GetFinalizers(), finalizers...))
+	return b
+}
+
+// WithCreationTimestamp sets the creation timestamp of the ProviderConfig.
+func (b *ProviderConfigBuilder) WithCreationTimestamp(t time.Time) *ProviderConfigBuilder {
+	b.obj.SetCreationTimestamp(metav1.NewTime(t))
+	return b
+}
+
+// Deleting sets the deletion timestamp of the ProviderConfig, as the API
+// server does when an object with finalizers is deleted.
+func (b *ProviderConfigBuilder) Deleting(t time.Time) *ProviderConfigBuilder {
+	deletionTimestamp := metav1.NewTime(t)
+	b.obj.SetDeletionTimestamp(&deletionTimestamp)
+	return b
+}
+
+// Build returns the ProviderConfig. The builder may be used to build more
+// ProviderConfigs afterwards; they do not share state.
+func (b *ProviderConfigBuilder) Build() *unstructured.Unstructured {
+	return b.obj.DeepCopy()
+}
+
+func merge(dst, src map[string]string) map[string]string {
+	if dst == nil {
+		dst = make(map[string]string, len(src))
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
@@ -0,0 +1,45 @@
+package frameworktest
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework"
+)
+
+// TestProviderConfigBuilder verifies the ProviderConfigs built by NewProviderConfig.
+func TestProviderConfigBuilder(t *testing.T) {
+	deleted := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	builder := NewProviderConfig("pc-1").
+		WithUID("uid-1").
+		WithLabels(map[string]string{"tier": "gold"}).
+		Paused().
+		WithSpecField("other-project", "projectID").
+		WithGeneration(3).
+		WithFinalizers("test-finalizer").
+		Deleting(deleted)
+	pc := builder.Build()
+
+	if got := pc.GroupVersionKind(); got != ProviderConfigGVK {
+		t.Errorf("GroupVersionKind() = %v, want %v", got, ProviderConfigGVK)
+	}
+	if pc.GetName() != "pc-1" || pc.GetUID() != "uid-1" || pc.GetGeneration() != 3 {
+		t.Errorf("Unexpected metadata %+v", pc.Object["metadata"])
+	}
+	if pc.GetLabels()["tier"] != "gold" || pc.GetAnnotations()[framework.PausedAnnotation] != "true" {
+		t.Errorf("Unexpected labels %v or annotations %v", pc.GetLabels(), pc.GetAnnotations())
+	}
+	if projectID, _, _ := unstructured.NestedString(pc.Object, "spec", "projectID"); projectID != "other-project" {
+		t.Errorf("spec.projectID = %q, want other-project", projectID)
+	}
+	if len(pc.GetFinalizers()) != 1 || !pc.GetDeletionTimestamp().Time.Equal(deleted) {
+		t.Errorf("Unexpected finalizers %v or deletion timestamp %v", pc.GetFinalizers(), pc.GetDeletionTimestamp())
+	}
+
+	pc.SetName("changed")
+	if got := builder.Build().GetName(); got != "pc-1" {
+		t.Errorf("Expected built ProviderConfigs not to share state, got name %q", got)
+	}
+}
@@ -0,0 +1,270 @@
+package frameworktest
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework"
+)
+
+// StartCall is a call of a RecordingStarter to start controllers.
+type StartCall struct {
+	// Key is the key of the ProviderConfig: its name, or namespace/name for
+	// namespaced tenant resources.
+	Key string
+	// ProviderConfig is a copy of the ProviderConfig the controllers were
+	// started for.
+	ProviderConfig *unstructured.Unstructured
+	// Err is the error the start failed with, if any.
+	Err error
+}
+
+// RecordingStarter is a framework.ControllerStarter that records the
+// controllers it starts and stops instead of running any. It implements
+// framework.ContextControllerStarter and framework.ReadinessReporter, and
+// injects faults on request: failed starts, controllers that crash, panic,
+// never become ready or hang on stop. Faults are set per ProviderConfig key
+// and it is safe for concurrent use.
+type RecordingStarter struct {
+	mu        sync.Mutex
+	calls     []StartCall
+	tenants   map[string]*recordedTenant
+	startErrs map[string]error
+	notReady  map[string]bool
+	hangStop  map[string]bool
+}
+
+// recordedTenant is what a RecordingStarter knows about the controllers of one
+// ProviderConfig.
+type recordedTenant struct {
+	starts  int
+	stops   int
+	crashes int
+	// current is the last started controllers, nil if they are not running.
+	current *recordedControllers
+	// hung are the controllers that were stopped but do not exit because of
+	// HangOnStop.
+	hung []*recordedControllers
+}
+
+// recordedControllers are controllers started by a RecordingStarter.
+type recordedControllers struct {
+	ctx       context.Context
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *recordedControllers) exit() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// NewRecordingStarter returns a RecordingStarter without faults.
+func NewRecordingStarter() *RecordingStarter {
+	return &RecordingStarter{
+		tenants:   map[string]*recordedTenant{},
+		startErrs: map[string]error{},
+		notReady:  map[string]bool{},
+		hangStop:  map[string]bool{},
+	}
+}
+
+// StartControllerWithContext implements framework.ContextControllerStarter.
+// The controllers run until ctx is cancelled.
+func (s *RecordingStarter) StartControllerWithContext(ctx context.Context, pc *unstructured.Unstructured) (<-chan struct{}, error) {
+	key := keyOf(pc)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.startErrs[key]
+	s.calls = append(s.calls, StartCall{Key: key, ProviderConfig: pc.DeepCopy(), Err: err})
+	if err != nil {
+		return nil, err
+	}
+	tenant := s.tenant(key)
+	tenant.starts++
+	controllers := &recordedControllers{ctx: ctx, done: make(chan struct{})}
+	tenant.current = controllers
+	context.AfterFunc(ctx, func() { s.stopped(key, controllers) })
+	return controllers.done, nil
+}
+
+// StartController implements framework.ControllerStarter. The framework calls
+// StartControllerWithContext instead; StartController is only used when the
+// RecordingStarter is adapted or called directly.
+func (s *RecordingStarter) StartController(pc *unstructured.Unstructured) (chan<- struct{}, error) {
+	return framework.AdaptContextControllerStarter(s).StartController(pc)
+}
+
+// HasSynced implements framework.ReadinessReporter. Controllers are ready
+// unless SetReady reported otherwise.
+func (s *RecordingStarter) HasSynced(pc *unstructured.Unstructured) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.notReady[keyOf(pc)]
+}
+
+// stopped records that controllers were asked to stop through their context.
+func (s *RecordingStarter) stopped(key string, controllers *recordedControllers) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tenant := s.tenant(key)
+	if tenant.current == controllers {
+		tenant.current = nil
+	}
+	select {
+	case <-controllers.done:
+		// The controllers crashed before they were asked to stop.
+		return
+	default:
+	}
+	tenant.stops++
+	if s.hangStop[key] {
+		tenant.hung = append(tenant.hung, controllers)
+		return
+	}
+	controllers.exit()
+}
+
+// tenant returns the record of key. s.mu must be held.
+func (s *RecordingStarter) tenant(key string) *recordedTenant {
+	tenant, ok := s.tenants[key]
+	if !ok {
+		tenant = &recordedTenant{}
+		s.tenants[key] = tenant
+	}
+	return tenant
+}
+
+// FailStarts makes the starts of the controllers for key fail with err. A nil
+// err lets them succeed again.
+func (s *RecordingStarter) FailStarts(key string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.startErrs, key)
+		return
+	}
+	s.startErrs[key] = err
+}
+
+// SetReady sets whether the controllers for key report themselves ready
+// through HasSynced. Controllers are ready by default.
+func (s *RecordingStarter) SetReady(key string, ready bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ready {
+		delete(s.notReady, key)
+		return
+	}
+	s.notReady[key] = true
+}
+
+// HangOnStop sets whether the controllers for key ignore requests to stop,
+// which exercises the stop and shutdown timeouts of the framework. Setting it
+// to false lets the controllers that are already hanging exit.
+func (s *RecordingStarter) HangOnStop(key string, hang bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if hang {
+		s.hangStop[key] = true
+		return
+	}
+	delete(s.hangStop, key)
+	if tenant, ok := s.tenants[key]; ok {
+		for _, controllers := range tenant.hung {
+			controllers.exit()
+		}
+		tenant.hung = nil
+	}
+}
+
+// Crash makes the running controllers for key exit without being asked to,
+// so that the framework restarts them. It reports whether controllers were
+// running.
+func (s *RecordingStarter) Crash(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tenant, ok := s.tenants[key]
+	if !ok || tenant.current == nil {
+		return false
+	}
+	tenant.crashes++
+	tenant.current.exit()
+	tenant.current = nil
+	return true
+}
+
+// Panic makes a goroutine of the running controllers for key, started with
+// framework.Go, panic with value. It reports whether controllers were running.
+func (s *RecordingStarter) Panic(key string, value any) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tenant, ok := s.tenants[key]
+	if !ok || tenant.current == nil {
+		return false
+	}
+	framework.Go(tenant.current.ctx, func(context.Context) {
+		panic(value)
+	})
+	return true
+}
+
+// Calls returns the calls to start controllers, in order.
+func (s *RecordingStarter) Calls() []StartCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]StartCall(nil), s.calls...)
+}
+
+// Starts returns how many times controllers for key were started successfully.
+func (s *RecordingStarter) Starts(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tenant, ok := s.tenants[key]; ok {
+		return tenant.starts
+	}
+	return 0
+}
+
+// Stops returns how many times controllers for key were asked to stop.
+func (s *RecordingStarter) Stops(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tenant, ok := s.tenants[key]; ok {
+		return tenant.stops
+	}
+	return 0
+}
+
+// Crashes returns how many times controllers for key crashed through Crash.
+func (s *RecordingStarter) Crashes(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tenant, ok := s.tenants[key]; ok {
+		return tenant.crashes
+	}
+	return 0
+}
+
+// Running reports whether controllers for key are running.
+func (s *RecordingStarter) Running(key string) bool {
+	return s.Context(key) != nil
+}
+
+// Context returns the context of the running controllers for key, or nil if
+// none are running.
+func (s *RecordingStarter) Context(key string) context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tenant, ok := s.tenants[key]; ok && tenant.current != nil {
+		return tenant.current.ctx
+	}
+	return nil
+}
+
+// keyOf returns the key of pc: its name, or namespace/name if it is namespaced.
+func keyOf(pc *unstructured.Unstructured) string {
+	return cache.MetaObjectToName(pc).String()
+}
@@ -0,0 +1,102 @@
+package frameworktest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+// TestRecordingStarter verifies the records of a RecordingStarter.
+func TestRecordingStarter(t *testing.T) {
+	starter := NewRecordingStarter()
+	pc := NewProviderConfig("pc-1").Build()
+
+	starter.FailStarts("pc-1", errors.New("injected start failure"))
+	if _, err := starter.StartControllerWithContext(context.Background(), pc); err == nil {
+		t.Fatal("Expected the start to fail")
+	}
+	starter.FailStarts("pc-1", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done, err := starter.StartControllerWithContext(ctx, pc)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !starter.Running("pc-1") || starter.Context("pc-1") != ctx {
+		t.Error("Expected the controllers to run with the context of the start")
+	}
+	cancel()
+	if !isClosed(done) {
+		t.Fatal("Expected the controllers to exit once their context is cancelled")
+	}
+	if starter.Running("pc-1") {
+		t.Error("Expected the controllers to be stopped")
+	}
+
+	calls := starter.Calls()
+	if len(calls) != 2 || calls[0].Err == nil || calls[1].Err != nil || calls[1].Key != "pc-1" {
+		t.Errorf("Unexpected calls %+v", calls)
+	}
+	if starts, stops := starter.Starts("pc-1"), starter.Stops("pc-1"); starts != 1 || stops != 1 {
+		t.Errorf("Starts, stops = %d, %d, want 1, 1", starts, stops)
+	}
+}
+
+// TestRecordingStarterHangOnStop verifies that controllers hanging on stop
+// exit once HangOnStop is cleared.
+func TestRecordingStarterHangOnStop(t *testing.T) {
+	starter := NewRecordingStarter()
+	starter.HangOnStop("pc-1", true)
+	ctx, cancel := context.WithCancel(context.Background())
+	done, err := starter.StartControllerWithContext(ctx, NewProviderConfig("pc-1").Build())
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("Expected the controllers to hang on stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+	starter.HangOnStop("pc-1", false)
+	if !isClosed(done) {
+		t.Error("Expected the controllers to exit once they no longer hang")
+	}
+}
+
+// TestRecordingStarterCrash verifies that crashed controllers exit without
+// being asked to stop.
+func TestRecordingStarterCrash(t *testing.T) {
+	starter := NewRecordingStarter()
+	if starter.Crash("pc-1") {
+		t.Error("Expected no controllers to crash before a start")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done, err := starter.StartControllerWithContext(ctx, NewProviderConfig("pc-1").Build())
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !starter.Crash("pc-1") {
+		t.Fatal("Expected the controllers to crash")
+	}
+	if !isClosed(done) {
+		t.Error("Expected crashed controllers to exit")
+	}
+	if ctx.Err() != nil {
+		t.Error("Expected the context of crashed controllers to stay active")
+	}
+	if got := starter.Crashes("pc-1"); got != 1 {
+		t.Errorf("Expected 1 crash, got %d", got)
+	}
+}
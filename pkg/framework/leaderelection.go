@@ -0,0 +1,148 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewDeadline = 10 * time.Second
+	defaultRetryPeriod   = 2 * time.Second
+)
+
+// LeaderElectionConfig enables Lease-based leader election for the Controller.
+// Only the replica holding the Lease processes ProviderConfigs and runs tenant
+// controllers.
+type LeaderElectionConfig struct {
+	// Client is used to acquire and renew the Lease.
+	Client coordinationv1client.LeasesGetter
+	// LeaseNamespace and LeaseName identify the Lease shared by all replicas.
+	LeaseNamespace string
+	LeaseName      string
+	// Identity uniquely identifies this replica. Defaults to the hostname.
+	Identity string
+	// LeaseDuration, RenewDeadline and RetryPeriod have the same meaning as in
+	// client-go's leaderelection package. Zero values select the defaults of
+	// 15s, 10s and 2s.
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// WithLeaderElection makes the Controller process ProviderConfigs only while it
+// holds the configured Lease. When the Lease is lost, the Controller stops the
+// controllers of every tenant, keeping their finalizers, and Run returns.
+func WithLeaderElection(cfg LeaderElectionConfig) Option {
+	return func(o *options) {
+		o.leaderElection = &cfg
+	}
+}
+
+// newLeaderElector builds a leader elector whose callbacks run onStartedLeading
+// and onStoppedLeading.
+func newLeaderElector(cfg LeaderElectionConfig, onStartedLeading func(context.Context), onStoppedLeading func()) (*leaderelection.LeaderElector, error) {
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("leader election requires a Lease client")
+	}
+	if cfg.LeaseNamespace == "" || cfg.LeaseName == "" {
+		return nil, fmt.Errorf("leader election requires a Lease namespace and name")
+	}
+	identity := cfg.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine leader election identity: %w", err)
+		}
+		identity = hostname
+	}
+	leaseDuration, renewDeadline, retryPeriod := cfg.LeaseDuration, cfg.RenewDeadline, cfg.RetryPeriod
+	if leaseDuration == 0 {
+		leaseDuration = defaultLeaseDuration
+	}
+	if renewDeadline == 0 {
+		renewDeadline = defaultRenewDeadline
+	}
+	if retryPeriod == 0 {
+		retryPeriod = defaultRetryPeriod
+	}
+
+	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Namespace: cfg.LeaseNamespace,
+				Name:      cfg.LeaseName,
+			},
+			Client:     cfg.Client,
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            providerConfigControllerName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: onStartedLeading,
+			OnStoppedLeading: onStoppedLeading,
+			OnNewLeader: func(identity string) {
+				klog.InfoS("Observed ProviderConfig Controller leader", "leader", identity)
+			},
+		},
+	})
+}
+
+// runWithLeaderElection runs the ProviderConfig workers only while this replica
+// holds the Lease. It returns once the Lease is lost or the stop channel is
+// closed, after the controllers of every tenant have been stopped.
+//
+// On shutdown the tenants are stopped before the elector's context is
+// cancelled, because cancelling it releases the Lease: the next leader must
+// not start controllers for tenants whose controllers still run here.
+func (c *Controller) runWithLeaderElection() {
+	var stopOnce sync.Once
+	// stopTenants drains the workers first so that no tenant is started
+	// concurrently, then stops every tenant so that the next leader takes over.
+	stopTenants := func(reason string) {
+		stopOnce.Do(func() {
+			c.providerConfigQueue.Shutdown()
+			c.stopAllTenants(reason)
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.stopCh:
+			stopTenants("shutdown")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	le, err := newLeaderElector(*c.leaderElection,
+		func(ctx context.Context) {
+			klog.InfoS("Acquired ProviderConfig Controller leadership", "numWorkers", c.workersCount)
+			c.providerConfigQueue.Run()
+			go c.runDriftReconciler(ctx.Done())
+		},
+		func() {
+			klog.InfoS("Stopped leading ProviderConfig Controller")
+			stopTenants("lost leadership")
+		},
+	)
+	if err != nil {
+		klog.ErrorS(err, "Failed to set up leader election for ProviderConfig Controller")
+		return
+	}
+	le.Run(ctx)
+}
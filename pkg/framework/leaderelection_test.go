@@ -0,0 +1,185 @@
+package framework
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	testLeaseNamespace = "kube-system"
+	testLeaseName      = "provider-config-controller"
+)
+
+func newLeaderElectionTestController(t *testing.T, client kubernetes.Interface) *testProviderConfigController {
+	t.Helper()
+	tc := newTestProviderConfigController(t)
+	fakeInformer := &fakeInformer{
+		Indexer: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}),
+		synced:  true,
+	}
+	tc.pcInformer = fakeInformer
+	tc.pcController = newController(tc.manager, fakeInformer, tc.stopCh, WithLeaderElection(LeaderElectionConfig{
+		Client:         client.CoordinationV1(),
+		LeaseNamespace: testLeaseNamespace,
+		LeaseName:      testLeaseName,
+		Identity:       "replica-a",
+		LeaseDuration:  400 * time.Millisecond,
+		RenewDeadline:  300 * time.Millisecond,
+		RetryPeriod:    50 * time.Millisecond,
+	}))
+	return tc
+}
+
+func testLeaderElectionProviderConfig(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "cloud.gke.io/v1",
+			"kind":       "ProviderConfig",
+			"metadata": map[string]any{
+				"name": name,
+			},
+		},
+	}
+}
+
+// TestLeaderElectionStopsTenantsWhenLeaseIsLost verifies that the leader processes
+// ProviderConfigs, and that losing the Lease stops every tenant and returns from Run.
+func TestLeaderElectionStopsTenantsWhenLeaseIsLost(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	tc := newLeaderElectionTestController(t, client)
+	defer close(tc.stopCh)
+
+	runDone := make(chan struct{})
+	go func() {
+		tc.pcController.Run()
+		close(runDone)
+	}()
+
+	addProviderConfig(t, tc, testLeaderElectionProviderConfig("pc-leader"))
+	if err := wait.PollImmediate(10*time.Millisecond, 2*time.Second, func() (bool, error) {
+		return tc.manager.HasStarted("pc-leader"), nil
+	}); err != nil {
+		t.Fatalf("Expected the leader to start 'pc-leader': %v", err)
+	}
+
+	// Another replica takes over the Lease.
+	lease, err := client.CoordinationV1().Leases(testLeaseNamespace).Get(context.TODO(), testLeaseName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get Lease: %v", err)
+	}
+	lease.Spec.HolderIdentity = ptr.To("replica-b")
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: time.Now()}
+	lease.Spec.LeaseDurationSeconds = ptr.To[int32](60)
+	if _, err := client.CoordinationV1().Leases(testLeaseNamespace).Update(context.TODO(), lease, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update Lease: %v", err)
+	}
+
+	select {
+	case <-runDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the Lease was lost")
+	}
+	if got := tc.manager.StopAllCount(); got != 1 {
+		t.Errorf("Expected tenant controllers to be stopped once after losing the Lease, got %d", got)
+	}
+}
+
+// TestLeaderElectionFollowerDoesNotProcess verifies that a replica that does not hold
+// the Lease never starts tenant controllers.
+func TestLeaderElectionFollowerDoesNotProcess(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testLeaseNamespace,
+			Name:      testLeaseName,
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To("replica-b"),
+			LeaseDurationSeconds: ptr.To[int32](60),
+			AcquireTime:          &metav1.MicroTime{Time: time.Now()},
+			RenewTime:            &metav1.MicroTime{Time: time.Now()},
+		},
+	})
+	tc := newLeaderElectionTestController(t, client)
+
+	runDone := make(chan struct{})
+	go func() {
+		tc.pcController.Run()
+		close(runDone)
+	}()
+
+	addProviderConfig(t, tc, testLeaderElectionProviderConfig("pc-follower"))
+	time.Sleep(300 * time.Millisecond)
+	if tc.manager.HasStarted("pc-follower") {
+		t.Error("Did not expect a follower to start tenant controllers")
+	}
+
+	close(tc.stopCh)
+	select {
+	case <-runDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after the stop channel was closed")
+	}
+}
+
+// TestLeaderElectionReleasesLeaseAfterTenantsStopped verifies that on shutdown
+// the leader stops every tenant before it releases the Lease, so that the next
+// leader cannot start controllers that still run on this replica.
+func TestLeaderElectionReleasesLeaseAfterTenantsStopped(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	tc := newLeaderElectionTestController(t, client)
+
+	released := make(chan int, 1)
+	client.PrependReactor("update", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		lease := action.(k8stesting.UpdateAction).GetObject().(*coordinationv1.Lease)
+		if ptr.Deref(lease.Spec.HolderIdentity, "") == "" {
+			select {
+			case released <- tc.manager.StopAllCount():
+			default:
+			}
+		}
+		return false, nil, nil
+	})
+
+	runDone := make(chan struct{})
+	go func() {
+		tc.pcController.Run()
+		close(runDone)
+	}()
+
+	addProviderConfig(t, tc, testLeaderElectionProviderConfig("pc-leader"))
+	if err := wait.PollImmediate(10*time.Millisecond, 2*time.Second, func() (bool, error) {
+		return tc.manager.HasStarted("pc-leader"), nil
+	}); err != nil {
+		t.Fatalf("Expected the leader to start 'pc-leader': %v", err)
+	}
+
+	close(tc.stopCh)
+	select {
+	case <-runDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the stop channel was closed")
+	}
+	select {
+	case stopped := <-released:
+		if stopped != 1 {
+			t.Errorf("Expected the tenants to be stopped before the Lease was released, got %d stops", stopped)
+		}
+	default:
+		t.Fatal("Expected the Lease to be released on shutdown")
+	}
+	if got := tc.manager.StopAllCount(); got != 1 {
+		t.Errorf("Expected tenant controllers to be stopped once on shutdown, got %d", got)
+	}
+}
@@ -2,43 +2,121 @@ package framework
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"k8s.io/klog/v2"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
+
+	mtcontext "github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework/mtcontext"
 )
 
 // manager coordinates lifecycle of controllers scoped to individual ProviderConfigs.
 // It ensures per-ProviderConfig controller startup is idempotent, adds/removes
-// finalizers, and wires stop channels for clean shutdown.
+// finalizers, wires stop channels for clean shutdown, and reports lifecycle
+// transitions through the ProviderConfig status conditions.
 //
 // This manager assumes it is invoked by a workqueue that guarantees
-// the same ProviderConfig key is never processed concurrently.
+// the same ProviderConfig key is never processed concurrently. It logs through
+// the logger of the context it is called with, which the caller scopes to the
+// ProviderConfig (see klog.FromContext).
 type manager struct {
 	controllers *ControllerMap
 
-	client            dynamic.Interface
-	finalizerName     string
-	controllerStarter ControllerStarter
+	client           dynamic.Interface
+	tenants          tenantResource
+	finalizerName    string
+	starters         []namedStarter
+	stopTimeout      time.Duration
+	specChangePolicy SpecChangePolicy
+
+	initialRestartBackoff time.Duration
+	maxRestartBackoff     time.Duration
+	clock                 clock.WithTicker
+	cleanupHook           CleanupHook
+	// readinessTimeout bounds how long started controllers may take to become
+	// ready before they are restarted; zero waits forever.
+	readinessTimeout time.Duration
+	// admission throttles tenant starts.
+	admission *startAdmission
+	// metrics records the lifecycle metrics of tenants.
+	metrics *managerMetrics
+	// recorder records Events on ProviderConfigs. It is nil unless Events are enabled.
+	recorder record.EventRecorder
+	// requeueAfter schedules another sync of the given ProviderConfig key. It is
+	// used to restart controllers that exited unexpectedly and may be nil.
+	requeueAfter func(key string, delay time.Duration)
+}
+
+// namedStarter is a ControllerStarter registered under a name, together with
+// the selector deciding which ProviderConfigs it runs for.
+type namedStarter struct {
+	name    string
+	starter ControllerStarter
+	// selector matches the labels of the ProviderConfigs the starter runs for.
+	// A nil selector matches every ProviderConfig.
+	selector labels.Selector
+}
+
+// enabledFor reports whether the starter runs for the given ProviderConfig.
+func (ns namedStarter) enabledFor(pc *unstructured.Unstructured) bool {
+	return ns.selector == nil || ns.selector.Matches(labels.Set(pc.GetLabels()))
 }
 
 // newManager constructs a new generic ProviderConfig controller manager.
 // It does not start any controllers until StartControllersForProviderConfig is invoked.
-func newManager(client dynamic.Interface, finalizerName string, controllerStarter ControllerStarter,
+func newManager(client dynamic.Interface, finalizerName string, controllerStarter ControllerStarter, opts ...Option,
 ) *manager {
+	o := newOptions(opts...)
+	var starters []namedStarter
+	if controllerStarter != nil {
+		starters = append(starters, namedStarter{name: DefaultControllerStarterName, starter: controllerStarter})
+	}
+	starters = append(starters, o.namedStarters...)
 	return &manager{
-		controllers:       NewControllerMap(),
-		client:            client,
-		finalizerName:     finalizerName,
-		controllerStarter: controllerStarter,
+		controllers:      NewControllerMap(),
+		client:           client,
+		tenants:          o.tenants,
+		finalizerName:    finalizerName,
+		starters:         starters,
+		stopTimeout:      o.stopTimeout,
+		specChangePolicy: o.specChangePolicy,
+
+		initialRestartBackoff: o.initialRestartBackoff,
+		maxRestartBackoff:     o.maxRestartBackoff,
+		readinessTimeout:      o.readinessTimeout,
+		clock:                 o.clock,
+		cleanupHook:           o.cleanupHook,
+		admission:             newStartAdmission(o.maxConcurrentStarts, o.startQPS, o.startBurst, o.startJitter, o.clock),
+		metrics:               newManagerMetrics(o.metricFactory),
 	}
 }
 
+// readinessPollInterval is how often ReadinessReporter.HasSynced is polled.
+const readinessPollInterval = 100 * time.Millisecond
+
+// stopCheckInterval is how often the sync of a ProviderConfig whose controllers
+// were asked to stop is requeued to check whether they exited.
+const stopCheckInterval = time.Second
+
 var providerConfigGVR = schema.GroupVersionResource{
 	Group:    "cloud.gke.io",
 	Version:  "v1",
@@ -51,132 +129,1116 @@ var providerConfigGVK = schema.GroupVersionKind{
 	Kind:    "ProviderConfig",
 }
 
-// providerConfigKey returns the key for a ProviderConfig in the controller map.
-func providerConfigKey(pc *unstructured.Unstructured) string {
-	return pc.GetName()
+// hashSpec returns a hash of the ProviderConfig spec, used to detect spec
+// changes independently of how the generation is maintained.
+func hashSpec(pc *unstructured.Unstructured) (string, error) {
+	b, err := json.Marshal(pc.Object["spec"])
+	if err != nil {
+		return "", err
+	}
+	h := fnv.New64a()
+	h.Write(b)
+	return strconv.FormatUint(h.Sum64(), 16), nil
 }
 
-func (m *manager) getProviderConfig(ctx context.Context, name string) (*unstructured.Unstructured, error) {
-	return m.client.Resource(providerConfigGVR).Get(ctx, name, metav1.GetOptions{})
+// getProviderConfig returns the latest version of pc from the API server.
+func (m *manager) getProviderConfig(ctx context.Context, pc *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	return m.tenants.resource(m.client, pc).Get(ctx, pc.GetName(), metav1.GetOptions{})
 }
 
 // rollbackFinalizerOnStartFailure removes the finalizer after a start failure
 // so that ProviderConfig deletion is not blocked.
 func (m *manager) rollbackFinalizerOnStartFailure(ctx context.Context, pc *unstructured.Unstructured, cause error) {
-	pcLatest, err := m.getProviderConfig(ctx, pc.GetName())
+	removed, err := m.removeFinalizer(ctx, pc)
 	if err != nil {
-		klog.Errorf("failed to get latest ProviderConfig for finalizer rollback: %v, originalError: %v", err, cause)
+		klog.FromContext(ctx).Error(err, "Failed to clean up finalizer after start failure", "originalError", cause)
 		return
 	}
-	finalizers := pcLatest.GetFinalizers()
-	newFinalizers := slices.DeleteFunc(finalizers, func(f string) bool { return f == m.finalizerName })
-	if len(newFinalizers) != len(finalizers) {
-		pcLatest.SetFinalizers(newFinalizers)
-		_, err := m.client.Resource(providerConfigGVR).Update(ctx, pcLatest, metav1.UpdateOptions{})
+	if removed {
+		m.recordEvent(pc, corev1.EventTypeNormal, ReasonFinalizerRemoved, "Removed finalizer %s after the controllers failed to start", m.finalizerName)
+	}
+}
+
+// updateStatusConditions merges the given conditions into the status of the
+// latest copy of the ProviderConfig. Conditions are stamped with the generation
+// of pc, which is the spec the framework acted on. Updates that conflict with
+// a concurrent change are retried on a fresh copy. Status updates are best
+// effort: failures are logged and never fail the lifecycle operation.
+func (m *manager) updateStatusConditions(ctx context.Context, pc *unstructured.Unstructured, conditions ...metav1.Condition) {
+	logger := klog.FromContext(ctx)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestPC, err := m.getProviderConfig(ctx, pc)
 		if err != nil {
-			klog.Errorf("failed to clean up finalizer after start failure: %v, originalError: %v", err, cause)
+			return err
+		}
+		changed, err := mergeConditions(latestPC, pc.GetGeneration(), conditions...)
+		if err != nil || !changed {
+			return err
+		}
+		_, err = m.tenants.resource(m.client, latestPC).UpdateStatus(ctx, latestPC, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.Error(err, "Failed to update status conditions")
+	}
+}
+
+// startControllers invokes the controller starter, preferring
+// ContextControllerStarter, then HandleControllerStarter, when the starter
+// implements them. For context starters, the returned cancel function must be
+// called once the controllers are stopped. Panics in goroutines that context
+// starters run through Go are reported to onPanic.
+func (m *manager) startControllers(ctx context.Context, ns namedStarter, pc *unstructured.Unstructured, onPanic panicHandler) (*ControllerHandle, context.CancelFunc, error) {
+	if cs, ok := ns.starter.(ContextControllerStarter); ok {
+		ctx, cancel := context.WithCancel(withPanicHandler(m.starterContext(ctx, ns, pc), onPanic))
+		done, err := cs.StartControllerWithContext(ctx, pc)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		// The stop channel is only closed by the framework; the controllers
+		// observe the stop through ctx.
+		return &ControllerHandle{StopCh: make(chan struct{}), Done: done}, cancel, nil
+	}
+	if hs, ok := ns.starter.(HandleControllerStarter); ok {
+		handle, err := hs.StartControllerWithHandle(pc)
+		return handle, nil, err
+	}
+	stopCh, err := ns.starter.StartController(pc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &ControllerHandle{StopCh: stopCh}, nil, nil
+}
+
+// starterContext returns the context passed to a ContextControllerStarter. It
+// keeps the values of ctx, such as the tenant UID and the logger of the sync,
+// but not its cancellation, since the controllers outlive the sync that starts
+// them.
+func (m *manager) starterContext(ctx context.Context, ns namedStarter, pc *unstructured.Unstructured) context.Context {
+	ctx = context.WithoutCancel(ctx)
+	if mtcontext.TenantUIDFromContext(ctx) == nil {
+		if tenantUID, err := m.tenants.tenantUID(pc); err == nil {
+			ctx = mtcontext.ContextWithTenantUID(ctx, tenantUID)
+		}
+	}
+	return klog.NewContext(ctx, klog.FromContext(ctx).WithValues("starter", ns.name))
+}
+
+// signalStop closes the stop channel of sc and records when the stop was requested.
+func (m *manager) signalStop(sc *starterControllers) {
+	close(sc.stopSignal)
+	close(sc.stopCh)
+	sc.mu.Lock()
+	sc.stopCh = nil
+	sc.mu.Unlock()
+	if sc.cancel != nil {
+		sc.cancel()
+		sc.cancel = nil
+	}
+	sc.stopRequested = m.clock.Now()
+}
+
+// watchForUnexpectedExit requeues the ProviderConfig key if the controllers
+// close done before the framework signals them to stop.
+func (m *manager) watchForUnexpectedExit(logger klog.Logger, pcKey string, done, stopSignal <-chan struct{}) {
+	select {
+	case <-stopSignal:
+		return
+	case <-done:
+	}
+	select {
+	case <-stopSignal:
+		return
+	default:
+	}
+	logger.Error(nil, "Controllers exited unexpectedly")
+	if m.requeueAfter != nil {
+		m.requeueAfter(pcKey, 0)
+	}
+}
+
+// restartDelay counts a failure of the controllers in sc and returns the
+// backoff after which they may be restarted.
+func (m *manager) restartDelay(sc *starterControllers) time.Duration {
+	if m.clock.Since(sc.startedAt) >= m.maxRestartBackoff {
+		sc.crashes = 0
+	}
+	delay := m.initialRestartBackoff
+	for i := 0; i < sc.crashes && delay < m.maxRestartBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, m.maxRestartBackoff)
+	sc.crashes++
+	return delay
+}
+
+// handleUnexpectedExit clears the state of controllers that exited on their own
+// and returns the backoff after which they may be restarted.
+func (m *manager) handleUnexpectedExit(sc *starterControllers) time.Duration {
+	delay := m.restartDelay(sc)
+	close(sc.stopSignal)
+	sc.mu.Lock()
+	sc.stopCh = nil
+	sc.mu.Unlock()
+	if sc.cancel != nil {
+		sc.cancel()
+		sc.cancel = nil
+	}
+	sc.done = nil
+	sc.restartAt = m.clock.Now().Add(delay)
+	return delay
+}
+
+// restartWithBackoff asks the running controllers in sc, e.g. controllers that
+// did not become ready in time or panicked, to stop and returns the backoff
+// after which they may be restarted.
+func (m *manager) restartWithBackoff(sc *starterControllers) time.Duration {
+	delay := m.restartDelay(sc)
+	m.signalStop(sc)
+	sc.restartAt = m.clock.Now().Add(delay)
+	return delay
+}
+
+// readinessTimedOut reports whether the running controllers in sc did not
+// become ready within the readiness timeout.
+func (m *manager) readinessTimedOut(sc *starterControllers) bool {
+	return m.readinessTimeout > 0 && sc.stopCh != nil && !sc.readiness.ready() && m.clock.Since(sc.startedAt) >= m.readinessTimeout
+}
+
+// watchReadiness waits until ready is closed and hasSynced returns true, if
+// they are set, then marks the controllers ready and requeues the
+// ProviderConfig key so that its status reports them ready. It gives up once
+// stopSignal is closed. It closes done when it returns.
+func (m *manager) watchReadiness(logger klog.Logger, pcKey string, r *readiness, ready <-chan struct{}, hasSynced func() bool, stopSignal <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	if ready != nil {
+		select {
+		case <-ready:
+		case <-stopSignal:
+			return
+		}
+	}
+	if hasSynced != nil {
+		ticker := m.clock.NewTicker(readinessPollInterval)
+		defer ticker.Stop()
+		for !hasSynced() {
+			select {
+			case <-ticker.C():
+			case <-stopSignal:
+				return
+			}
+		}
+	}
+	r.markReady(m.clock.Now())
+	logger.Info("Controllers are ready")
+	if m.requeueAfter != nil {
+		m.requeueAfter(pcKey, 0)
+	}
+}
+
+// applySpecChange reacts to a changed spec of a ProviderConfig whose controllers
+// are running, according to the configured SpecChangePolicy. It returns true
+// if the controllers were asked to stop and must be started again.
+func (m *manager) applySpecChange(ctx context.Context, pc *unstructured.Unstructured, ns namedStarter, sc *starterControllers, specHash string) (bool, error) {
+	pcKey := m.tenants.key(pc)
+	logger := klog.FromContext(ctx).WithValues("starter", ns.name)
+	switch m.specChangePolicy {
+	case SpecChangePolicyIgnore:
+		logger.V(2).Info("Spec changed; leaving running controllers untouched")
+		return false, nil
+	case SpecChangePolicyUpdate:
+		updater, ok := ns.starter.(ControllerUpdater)
+		if !ok {
+			break
+		}
+		if err := updater.UpdateController(pc); err != nil {
+			return false, fmt.Errorf("failed to update controllers %s for provider config %s: %w", ns.name, pcKey, err)
+		}
+		sc.generation = pc.GetGeneration()
+		sc.specHash = specHash
+		logger.Info("Updated controllers", "generation", sc.generation)
+		return false, nil
+	}
+	logger.Info("Spec changed; restarting controllers", "oldGeneration", sc.generation, "generation", pc.GetGeneration())
+	m.signalStop(sc)
+	return true, nil
+}
+
+// stopProgress reports, without blocking, whether the controllers in sc that
+// were asked to stop have exited. If they have not, wait is how long until the
+// stop timeout, measured from when the stop was requested, expires; it is not
+// positive once the timeout expired.
+func (m *manager) stopProgress(sc *starterControllers) (exited bool, wait time.Duration) {
+	if sc.done == nil {
+		return true, 0
+	}
+	select {
+	case <-sc.done:
+		return true, 0
+	default:
+	}
+	return false, m.stopTimeout - m.clock.Since(sc.stopRequested)
+}
+
+// signalAndCheckStop signals the running controllers of the named starters in
+// cs to stop and reports, without blocking, which of them are still stopping
+// and which did not exit within the stop timeout. If any are stopping, recheck
+// is how long until the first of their stop timeouts expires.
+func (m *manager) signalAndCheckStop(logger klog.Logger, cs *ControllerSet, names []string) (stopping, timedOut []string, recheck time.Duration) {
+	for _, name := range names {
+		sc := cs.controllersFor(name)
+		if sc.stopCh != nil {
+			m.signalStop(sc)
+			logger.Info("Signaled controllers to stop", "starter", name)
 		}
 	}
+	for _, name := range names {
+		exited, wait := m.stopProgress(cs.controllersFor(name))
+		switch {
+		case exited:
+		case wait > 0:
+			stopping = append(stopping, name)
+			if recheck == 0 || wait < recheck {
+				recheck = wait
+			}
+		default:
+			timedOut = append(timedOut, name)
+		}
+	}
+	return stopping, timedOut, recheck
+}
+
+// requeueStopCheck requeues the sync of pcKey to check again whether the
+// controllers that are stopping have exited. Rather than blocking a worker
+// until they do, the callers return and keep the controller map entry.
+func (m *manager) requeueStopCheck(logger klog.Logger, pcKey string, stopping []string, recheck time.Duration) {
+	recheck = min(recheck, stopCheckInterval)
+	logger.Info("Waiting for controllers to exit", "starters", stopping, "recheckAfter", recheck)
+	if m.requeueAfter != nil {
+		m.requeueAfter(pcKey, recheck)
+	}
+}
+
+// stoppingCondition returns the ControllersRunning condition reporting the
+// starters whose controllers are stopping.
+func stoppingCondition(stopping []string) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionControllersRunning,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonControllersStopping,
+		Message: fmt.Sprintf("Waiting for controllers %s to exit", strings.Join(stopping, ", ")),
+	}
+}
+
+// waitForControllersToExit blocks until the controllers in sc have exited or
+// the stop timeout, measured from when the stop was requested, expires.
+// It returns true if the controllers exited in time. An error is returned
+// only if ctx is cancelled first.
+func (m *manager) waitForControllersToExit(ctx context.Context, sc *starterControllers) (bool, error) {
+	if sc.done == nil {
+		return true, nil
+	}
+	select {
+	case <-sc.done:
+		return true, nil
+	default:
+	}
+	remaining := m.stopTimeout - m.clock.Since(sc.stopRequested)
+	if remaining <= 0 {
+		return false, nil
+	}
+	timer := m.clock.NewTimer(remaining)
+	defer timer.Stop()
+	select {
+	case <-sc.done:
+		return true, nil
+	case <-timer.C():
+		return false, nil
+	case <-ctx.Done():
+		return false, fmt.Errorf("interrupted while waiting for controllers to stop: %w", ctx.Err())
+	}
+}
+
+// stopDisabledStarters stops the controllers of starters that no longer run
+// for the ProviderConfig, e.g. because its labels changed. It does not wait for
+// them to exit: the starters whose controllers are still stopping stay tracked,
+// and the ProviderConfig is requeued to check again.
+func (m *manager) stopDisabledStarters(ctx context.Context, pc *unstructured.Unstructured, cs *ControllerSet) {
+	logger := klog.FromContext(ctx)
+	var disabled []string
+	for _, name := range cs.Starters() {
+		if slices.ContainsFunc(m.starters, func(ns namedStarter) bool { return ns.name == name && ns.enabledFor(pc) }) {
+			continue
+		}
+		if cs.controllersFor(name).stopCh != nil {
+			logger.Info("Controllers are no longer enabled; stopping them", "starter", name)
+		}
+		disabled = append(disabled, name)
+	}
+	if len(disabled) == 0 {
+		return
+	}
+	stopping, timedOut, recheck := m.signalAndCheckStop(logger, cs, disabled)
+	if len(timedOut) > 0 {
+		logger.Error(nil, "Controllers did not exit in time", "starters", timedOut, "stopTimeout", m.stopTimeout)
+	}
+	for _, name := range disabled {
+		if !slices.Contains(stopping, name) {
+			cs.removeControllers(name)
+		}
+	}
+	if len(stopping) > 0 {
+		m.requeueStopCheck(logger, m.tenants.key(pc), stopping, recheck)
+	}
 }
 
 // StartControllersForProviderConfig ensures finalizers are present and starts
-// the controllers associated with the given ProviderConfig. The call is
-// idempotent: repeated calls for the same ProviderConfig will only start
-// controllers once.
+// the controllers of every starter enabled for the given ProviderConfig. The
+// call is idempotent: repeated calls for the same ProviderConfig will only start
+// controllers once, unless the spec changed since they were started, in which
+// case the configured SpecChangePolicy applies. Controllers of starters that
+// are no longer enabled for the ProviderConfig are stopped.
 func (m *manager) StartControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) error {
-	if pc.GroupVersionKind() != providerConfigGVK {
-		return fmt.Errorf("expected object of kind %s, but got %s", providerConfigGVK, pc.GroupVersionKind())
+	if err := m.tenants.checkKind(pc); err != nil {
+		return err
 	}
+	logger := klog.FromContext(ctx)
 	if !pc.GetDeletionTimestamp().IsZero() {
-		klog.InfoDepth(3, "ProviderConfig is terminating; skipping start")
+		logger.Info("ProviderConfig is terminating; skipping start")
 		return nil
 	}
 
-	pcKey := providerConfigKey(pc)
+	pcKey := m.tenants.key(pc)
+
+	specHash, err := hashSpec(pc)
+	if err != nil {
+		return fmt.Errorf("failed to hash spec of provider config %s: %w", pcKey, err)
+	}
 
 	cs, existed := m.controllers.GetOrCreate(pcKey)
-	if cs.stopCh != nil {
-		klog.Info("Controllers for provider config already exist, skipping start")
-		return nil
+	if tenantUID, err := m.tenants.tenantUID(pc); err == nil {
+		cs.setTenantUID(tenantUID)
 	}
+	var errs []error
+	m.stopDisabledStarters(ctx, pc, cs)
 
-	klog.Info("Starting controllers for provider config")
+	var toStart []namedStarter
+	var crashMessages []string
+	var readinessMessages []string
+	var panicMessages []string
+	updated := false
+	for _, ns := range m.starters {
+		if !ns.enabledFor(pc) {
+			continue
+		}
+		sc := cs.controllersFor(ns.name)
+		if sc.exitedUnexpectedly() {
+			delay := m.handleUnexpectedExit(sc)
+			logger.Error(nil, "Controllers exited unexpectedly; restarting them after a backoff", "starter", ns.name, "delay", delay)
+			crashMessages = append(crashMessages, fmt.Sprintf("controllers %s exited unexpectedly; restarting in %v", ns.name, delay))
+			if m.requeueAfter != nil {
+				m.requeueAfter(pcKey, delay)
+			}
+		}
+		if sc.hasPanicked() {
+			delay := m.restartWithBackoff(sc)
+			logger.Error(nil, "Controllers panicked; restarting them after a backoff", "starter", ns.name, "delay", delay)
+			panicMessages = append(panicMessages, fmt.Sprintf("controllers %s panicked; restarting in %v", ns.name, delay))
+			m.metrics.startFailures.WithLabelValues(ReasonControllersPanicked).Inc()
+			if m.requeueAfter != nil {
+				m.requeueAfter(pcKey, delay)
+			}
+		}
+		if m.readinessTimedOut(sc) {
+			delay := m.restartWithBackoff(sc)
+			logger.Error(nil, "Controllers did not become ready in time; restarting them after a backoff", "starter", ns.name, "readinessTimeout", m.readinessTimeout, "delay", delay)
+			readinessMessages = append(readinessMessages, fmt.Sprintf("controllers %s did not become ready within %v; restarting in %v", ns.name, m.readinessTimeout, delay))
+			m.metrics.startFailures.WithLabelValues(ReasonReadinessTimedOut).Inc()
+			if m.requeueAfter != nil {
+				m.requeueAfter(pcKey, delay)
+			}
+		}
+		if wait := sc.restartAt.Sub(m.clock.Now()); wait > 0 {
+			logger.Info("Controllers are backing off after an unexpected exit", "starter", ns.name, "delay", wait)
+			continue
+		}
+		if sc.stopCh != nil {
+			if sc.specHash == specHash {
+				sc.generation = pc.GetGeneration()
+				logger.Info("Controllers already exist, skipping start", "starter", ns.name)
+				continue
+			}
+			restart, err := m.applySpecChange(ctx, pc, ns, sc, specHash)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !restart {
+				updated = updated || m.specChangePolicy == SpecChangePolicyUpdate
+				continue
+			}
+		}
+		if !sc.stopRequested.IsZero() {
+			// The previous controllers were asked to stop, e.g. for a restart, but
+			// have not been observed to exit yet. Rather than blocking the worker,
+			// the sync is requeued to check again.
+			exited, wait := m.stopProgress(sc)
+			if !exited && wait > 0 {
+				logger.Info("Waiting for controllers to exit before starting them again", "starter", ns.name, "recheckAfter", min(wait, stopCheckInterval))
+				if m.requeueAfter != nil {
+					m.requeueAfter(pcKey, min(wait, stopCheckInterval))
+				}
+				continue
+			}
+			if !exited {
+				logger.Error(nil, "Controllers did not exit in time; starting new controllers anyway", "starter", ns.name, "stopTimeout", m.stopTimeout)
+			}
+			sc.done = nil
+			sc.stopRequested = time.Time{}
+		}
+		toStart = append(toStart, ns)
+	}
 
-	finalizers := pc.GetFinalizers()
-	hadFinalizer := slices.Contains(finalizers, m.finalizerName)
+	if len(crashMessages) > 0 {
+		m.updateStatusConditions(ctx, pc, metav1.Condition{
+			Type:    ConditionControllersRunning,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonControllersExited,
+			Message: strings.Join(crashMessages, "; "),
+		})
+	}
+	if len(readinessMessages) > 0 {
+		cs.readyReported = false
+		m.updateStatusConditions(ctx, pc, metav1.Condition{
+			Type:    ConditionControllersReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonReadinessTimedOut,
+			Message: strings.Join(readinessMessages, "; "),
+		})
+	}
+	if len(panicMessages) > 0 {
+		cs.readyReported = false
+		m.updateStatusConditions(ctx, pc, metav1.Condition{
+			Type:    ConditionDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  ReasonControllersPanicked,
+			Message: strings.Join(panicMessages, "; "),
+		})
+	}
+	if len(toStart) == 0 {
+		if !existed && len(cs.Starters()) == 0 {
+			// No starter is enabled for the ProviderConfig. It is not tracked,
+			// so that drift, debug and cleanup do not treat it as a tenant.
+			m.controllers.Delete(pcKey)
+			return errors.Join(errs...)
+		}
+		if cs.running() && !m.HasFinalizer(pc) {
+			// The finalizer was removed while the controllers are running, e.g.
+			// by a user. Re-add it so that deletion still stops the controllers.
+			if err := m.addFinalizer(ctx, pc); err != nil {
+				errs = append(errs, err)
+			} else {
+				logger.Info("Re-added finalizer", "finalizer", m.finalizerName)
+			}
+		}
+		if updated && len(errs) == 0 {
+			m.updateStatusConditions(ctx, pc, metav1.Condition{
+				Type:    ConditionControllersRunning,
+				Status:  metav1.ConditionTrue,
+				Reason:  ReasonControllersUpdated,
+				Message: "Controllers for the ProviderConfig are running with the updated spec",
+			})
+		}
+		if cs.running() && !cs.readyReported && cs.ready() {
+			m.updateStatusConditions(ctx, pc, readyConditions(cs)...)
+			cs.readyReported = true
+		}
+		return errors.Join(errs...)
+	}
 
+	release, wait, admitted := m.admission.admit(pcKey)
+	if !admitted {
+		logger.Info("Start of controllers is pending", "retryAfter", wait)
+		if !cs.running() {
+			if !existed {
+				m.controllers.Delete(pcKey)
+			}
+			m.updateStatusConditions(ctx, pc, metav1.Condition{
+				Type:    ConditionControllersRunning,
+				Status:  metav1.ConditionFalse,
+				Reason:  ReasonStartPending,
+				Message: "Controllers for the ProviderConfig are waiting for their turn to start",
+			})
+		}
+		if m.requeueAfter != nil {
+			m.requeueAfter(pcKey, wait)
+		}
+		return errors.Join(errs...)
+	}
+	// The start keeps its slot until the controllers it started are ready, so
+	// that the concurrent starts also bound the tenants syncing their caches.
+	var starting []<-chan struct{}
+	defer func() {
+		m.releaseWhenReady(release, starting)
+	}()
+	startTime := m.clock.Now()
+
+	logger.Info("Starting controllers", "starters", len(toStart))
+
+	hadFinalizer := m.HasFinalizer(pc)
 	if !hadFinalizer {
-		pc.SetFinalizers(append(finalizers, m.finalizerName))
-		_, err := m.client.Resource(providerConfigGVR).Update(ctx, pc, metav1.UpdateOptions{})
+		if err := m.addFinalizer(ctx, pc); err != nil {
+			if !existed {
+				m.controllers.Delete(pcKey)
+			}
+			m.updateStatusConditions(ctx, pc, startFailedConditions(ReasonFinalizerUpdateFailed, err)...)
+			m.metrics.startFailures.WithLabelValues(ReasonFinalizerUpdateFailed).Inc()
+			return errors.Join(append(errs, err)...)
+		}
+	}
+
+	var startErrs []error
+	for _, ns := range toStart {
+		sc := cs.controllersFor(ns.name)
+		panicked := &atomic.Bool{}
+		stopSignal := make(chan struct{})
+		handle, cancel, err := m.startControllers(ctx, ns, pc, m.panicHandlerFor(pcKey, ns.name, cs, sc, panicked, stopSignal))
+		if err == nil && (handle == nil || handle.StopCh == nil) {
+			err = fmt.Errorf("controller starter returned nil channel")
+		}
 		if err != nil {
+			close(stopSignal)
+			startErrs = append(startErrs, fmt.Errorf("failed to start controller %s for provider config %s: %w", ns.name, pcKey, err))
+			continue
+		}
+
+		sc.mu.Lock()
+		sc.stopCh = handle.StopCh
+		sc.cancel = cancel
+		sc.done = handle.Done
+		sc.generation = pc.GetGeneration()
+		sc.specHash = specHash
+		sc.stopSignal = stopSignal
+		sc.panicked = panicked
+		sc.startedAt = m.clock.Now()
+		sc.readiness = &readiness{}
+		sc.mu.Unlock()
+		if !sc.restartAt.IsZero() {
+			sc.restartAt = time.Time{}
+			sc.restarts.Add(1)
+		}
+		if sc.done != nil {
+			go m.watchForUnexpectedExit(logger.WithValues("starter", ns.name), pcKey, sc.done, sc.stopSignal)
+		}
+		if watched := m.trackReadiness(logger.WithValues("starter", ns.name), pcKey, ns, sc, handle.Ready, pc); watched != nil {
+			starting = append(starting, watched)
+		}
+		logger.Info("Started controllers", "starter", ns.name)
+	}
+
+	if len(startErrs) > 0 {
+		if !cs.running() {
 			if !existed {
 				m.controllers.Delete(pcKey)
 			}
-			return fmt.Errorf("failed to ensure finalizer %s for provider config %s: %w", m.finalizerName, pcKey, err)
+			if !hadFinalizer {
+				m.rollbackFinalizerOnStartFailure(ctx, pc, errors.Join(startErrs...))
+			}
 		}
+		err := errors.Join(startErrs...)
+		m.recordEvent(pc, corev1.EventTypeWarning, ReasonControllerStartFailed, "Failed to start controllers: %v", err)
+		m.updateStatusConditions(ctx, pc, startFailedConditions(ReasonControllerStartFailed, err)...)
+		m.metrics.startFailures.WithLabelValues(ReasonControllerStartFailed).Inc()
+		return errors.Join(append(errs, err)...)
 	}
 
-	controllerStopCh, err := m.controllerStarter.StartController(pc)
-	if err == nil && controllerStopCh == nil {
-		err = fmt.Errorf("controller starter returned nil channel")
+	names := make([]string, 0, len(toStart))
+	for _, ns := range toStart {
+		names = append(names, ns.name)
 	}
-	if err != nil {
-		if !existed {
-			m.controllers.Delete(pcKey)
+	m.recordEvent(pc, corev1.EventTypeNormal, ReasonControllersStarted, "Started controllers %s", strings.Join(names, ", "))
+	m.metrics.startDuration.Observe(m.clock.Since(startTime).Seconds())
+	// The finalizer is only missing before the first start of a tenant.
+	if created := pc.GetCreationTimestamp(); !hadFinalizer && !created.IsZero() {
+		m.metrics.timeToRunning.Observe(m.clock.Since(created.Time).Seconds())
+	}
+
+	if len(errs) == 0 {
+		conditions := []metav1.Condition{
+			{
+				Type:    ConditionControllersRunning,
+				Status:  metav1.ConditionTrue,
+				Reason:  ReasonControllersStarted,
+				Message: m.runningMessage(cs),
+			},
+			{
+				Type:    ConditionStartFailed,
+				Status:  metav1.ConditionFalse,
+				Reason:  ReasonControllersStarted,
+				Message: "Controllers for the ProviderConfig started successfully",
+			},
 		}
-		if !hadFinalizer {
-			m.rollbackFinalizerOnStartFailure(ctx, pc, err)
+		if wasPaused(pc) {
+			conditions = append(conditions, metav1.Condition{
+				Type:    ConditionPaused,
+				Status:  metav1.ConditionFalse,
+				Reason:  ReasonResumed,
+				Message: "Controllers for the ProviderConfig were resumed",
+			})
 		}
-		return fmt.Errorf("failed to start controller for provider config %s: %w", pcKey, err)
+		conditions = append(conditions, readyConditions(cs)...)
+		cs.readyReported = cs.ready()
+		m.updateStatusConditions(ctx, pc, conditions...)
 	}
 
-	cs.stopCh = controllerStopCh
+	logger.Info("Started controllers for provider config", "duration", m.clock.Since(startTime))
+	return errors.Join(errs...)
+}
+
+// trackReadiness tracks the readiness of the controllers just started in sc.
+// Controllers that report readiness through ready or a ReadinessReporter are
+// watched until they are ready, and the returned channel is closed once the
+// watch ends; the others are ready right away and nil is returned.
+func (m *manager) trackReadiness(logger klog.Logger, pcKey string, ns namedStarter, sc *starterControllers, ready <-chan struct{}, pc *unstructured.Unstructured) <-chan struct{} {
+	var hasSynced func() bool
+	if rr, ok := readinessReporterOf(ns.starter); ok {
+		hasSynced = func() bool { return rr.HasSynced(pc) }
+	}
+	if ready == nil && hasSynced == nil {
+		sc.readiness.markReady(sc.startedAt)
+		return nil
+	}
+	done := make(chan struct{})
+	go m.watchReadiness(logger, pcKey, sc.readiness, ready, hasSynced, sc.stopSignal, done)
+	if m.readinessTimeout > 0 && m.requeueAfter != nil {
+		m.requeueAfter(pcKey, m.readinessTimeout)
+	}
+	return done
+}
 
-	klog.Info("Started controllers for provider config")
+// releaseWhenReady releases the admission slot of a start once the readiness
+// watches in starting have ended, i.e. the controllers are ready or stopped,
+// or once the readiness timeout expires. Without a readiness timeout, the slot
+// is released after defaultReadinessTimeout, so that tenants that never become
+// ready do not hold up the starts of all others. It releases the slot right
+// away if nothing is starting.
+func (m *manager) releaseWhenReady(release func(), starting []<-chan struct{}) {
+	if len(starting) == 0 {
+		release()
+		return
+	}
+	slotTimeout := m.readinessTimeout
+	if slotTimeout == 0 {
+		slotTimeout = defaultReadinessTimeout
+	}
+	go func() {
+		defer release()
+		timer := m.clock.NewTimer(slotTimeout)
+		defer timer.Stop()
+		for _, done := range starting {
+			select {
+			case <-done:
+			case <-timer.C():
+				return
+			}
+		}
+	}()
+}
+
+// readyCondition returns the ControllersReady condition describing cs.
+func readyCondition(cs *ControllerSet) metav1.Condition {
+	if notReady := cs.notReady(); len(notReady) > 0 {
+		return metav1.Condition{
+			Type:    ConditionControllersReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonControllersStarting,
+			Message: fmt.Sprintf("Waiting for controllers %s to become ready", strings.Join(notReady, ", ")),
+		}
+	}
+	return metav1.Condition{
+		Type:    ConditionControllersReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  ReasonControllersReady,
+		Message: "Controllers for the ProviderConfig are ready",
+	}
+}
+
+// readyConditions returns the ControllersReady condition describing cs and,
+// once the controllers of a degraded tenant are ready again, clears the
+// degraded mark of cs and returns the Degraded condition reporting the recovery.
+func readyConditions(cs *ControllerSet) []metav1.Condition {
+	conditions := []metav1.Condition{readyCondition(cs)}
+	if cs.ready() && cs.degraded.CompareAndSwap(true, false) {
+		conditions = append(conditions, metav1.Condition{
+			Type:    ConditionDegraded,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonControllersRecovered,
+			Message: "Controllers for the ProviderConfig recovered after a panic",
+		})
+	}
+	return conditions
+}
+
+// HasFinalizer reports whether pc carries the finalizer of the manager.
+func (m *manager) HasFinalizer(pc *unstructured.Unstructured) bool {
+	return slices.Contains(pc.GetFinalizers(), m.finalizerName)
+}
+
+// addFinalizer adds the finalizer of the manager to the ProviderConfig. pc may
+// be stale; conflicts are retried with the latest copy.
+func (m *manager) addFinalizer(ctx context.Context, pc *unstructured.Unstructured) error {
+	added, err := m.ensureFinalizer(ctx, pc)
+	if err != nil {
+		err = fmt.Errorf("failed to ensure finalizer %s for provider config %s: %w", m.finalizerName, m.tenants.key(pc), err)
+		m.recordEvent(pc, corev1.EventTypeWarning, ReasonFinalizerUpdateFailed, "Failed to add finalizer: %v", err)
+		return err
+	}
+	if added {
+		m.recordEvent(pc, corev1.EventTypeNormal, ReasonFinalizerAdded, "Added finalizer %s", m.finalizerName)
+	}
 	return nil
 }
 
-// StopControllersForProviderConfig stops the controllers for the given ProviderConfig
-// and removes the associated finalizer. Finalizer removal is attempted even if no
-// controller mapping exists, ensuring deletion can proceed after process restarts
-// or when controllers were previously stopped.
+// TrackedProviderConfigs returns the keys of the ProviderConfigs that have
+// controllers, mapped to whether any of their controllers are running.
+func (m *manager) TrackedProviderConfigs() map[string]bool {
+	tracked := map[string]bool{}
+	for _, key := range m.controllers.Keys() {
+		if cs, ok := m.controllers.Get(key); ok {
+			tracked[key] = cs.running()
+		}
+	}
+	return tracked
+}
+
+// PendingProviderConfigs returns the keys of the ProviderConfigs whose start
+// is pending admission, see WithMaxConcurrentStarts and WithStartRateLimit.
+func (m *manager) PendingProviderConfigs() sets.Set[string] {
+	return m.admission.pendingKeys()
+}
+
+// TenantUID returns the tenant UID recorded when the controllers of the
+// ProviderConfig with the given key were started, or "" if none is known.
+func (m *manager) TenantUID(key string) string {
+	if cs, ok := m.controllers.Get(key); ok {
+		return cs.getTenantUID()
+	}
+	return ""
+}
+
+// runningMessage describes the running controllers of cs for the ControllersRunning condition.
+func (m *manager) runningMessage(cs *ControllerSet) string {
+	var restarted []string
+	for _, name := range cs.Starters() {
+		if restarts := cs.Restarts(name); restarts > 0 {
+			restarted = append(restarted, fmt.Sprintf("%s restarted %d time(s)", name, restarts))
+		}
+	}
+	if len(restarted) == 0 {
+		return "Controllers for the ProviderConfig are running"
+	}
+	return fmt.Sprintf("Controllers for the ProviderConfig are running (%s)", strings.Join(restarted, ", "))
+}
+
+// StopControllersForProviderConfig stops the controllers of every starter for the given
+// ProviderConfig and removes the associated finalizer. If a starter reports when its
+// controllers exit, the finalizer is kept until they do or until the stop timeout expires.
+// The call does not wait for them: while they are stopping, the ControllersRunning
+// condition reports ReasonControllersStopping and the ProviderConfig is requeued to
+// check again.
+// Finalizer removal is attempted even if no controller mapping exists, ensuring
+// deletion can proceed after process restarts or when controllers were previously stopped.
 func (m *manager) StopControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) error {
-	if pc.GroupVersionKind() != providerConfigGVK {
-		return fmt.Errorf("expected object of kind %s, but got %s", providerConfigGVK, pc.GroupVersionKind())
+	if err := m.tenants.checkKind(pc); err != nil {
+		return err
 	}
-	pcKey := providerConfigKey(pc)
+	pcKey := m.tenants.key(pc)
+	logger := klog.FromContext(ctx)
+	stopTime := m.clock.Now()
+	m.admission.forget(pcKey)
+
+	m.updateStatusConditions(ctx, pc, metav1.Condition{
+		Type:    ConditionTerminating,
+		Status:  metav1.ConditionTrue,
+		Reason:  ReasonDeletionRequested,
+		Message: "ProviderConfig is being deleted",
+	})
 
+	stoppedCondition := metav1.Condition{
+		Type:    ConditionControllersRunning,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonControllersStopped,
+		Message: "Controllers for the ProviderConfig have been stopped",
+	}
 	if cs, exists := m.controllers.Get(pcKey); exists {
+		stopping, timedOut, recheck := m.signalAndCheckStop(logger, cs, cs.Starters())
+		for _, name := range cs.Starters() {
+			// The stop may have been requested by an earlier sync.
+			if sc := cs.controllersFor(name); !sc.stopRequested.IsZero() && sc.stopRequested.Before(stopTime) {
+				stopTime = sc.stopRequested
+			}
+		}
+		if len(stopping) > 0 {
+			// The finalizer is kept until the controllers exited.
+			m.updateStatusConditions(ctx, pc, stoppingCondition(stopping))
+			m.requeueStopCheck(logger, pcKey, stopping, recheck)
+			return nil
+		}
 		m.controllers.Delete(pcKey)
-		if cs.stopCh != nil {
-			close(cs.stopCh)
-			klog.Info("Signaled controller stop")
-		} else {
-			klog.Info("Controllers for provider config already stopped")
+		if len(timedOut) > 0 {
+			m.metrics.stopFailures.WithLabelValues(ReasonStopTimedOut).Inc()
+			logger.Error(nil, "Controllers did not exit in time; removing finalizer anyway", "starters", timedOut, "stopTimeout", m.stopTimeout)
+			stoppedCondition.Status = metav1.ConditionUnknown
+			stoppedCondition.Reason = ReasonStopTimedOut
+			stoppedCondition.Message = fmt.Sprintf("Controllers %s for the ProviderConfig did not exit within %v", strings.Join(timedOut, ", "), m.stopTimeout)
 		}
 	} else {
-		klog.Info("Controllers for provider config do not exist")
+		logger.Info("Controllers for provider config do not exist")
+	}
+
+	m.updateStatusConditions(ctx, pc, stoppedCondition)
+	if stoppedCondition.Reason == ReasonStopTimedOut {
+		m.recordEvent(pc, corev1.EventTypeWarning, ReasonStopTimedOut, "%s", stoppedCondition.Message)
+	} else {
+		m.recordEvent(pc, corev1.EventTypeNormal, ReasonControllersStopped, "%s", stoppedCondition.Message)
 	}
 
-	// Fetch the latest ProviderConfig to ensure we have current finalizer state.
-	latestPC, err := m.getProviderConfig(ctx, pc.GetName())
+	if err := m.runCleanupHook(ctx, pcKey); err != nil {
+		m.metrics.stopFailures.WithLabelValues(stopReasonCleanupFailed).Inc()
+		return err
+	}
+
+	// The finalizer is removed from the latest ProviderConfig, retrying on
+	// conflicts with concurrent writers.
+	removed, err := m.removeFinalizer(ctx, pc)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			klog.Info("ProviderConfig not found while stopping controllers; skipping finalizer removal")
+		m.recordEvent(pc, corev1.EventTypeWarning, ReasonFinalizerUpdateFailed, "Failed to remove finalizer: %v", err)
+		m.metrics.stopFailures.WithLabelValues(ReasonFinalizerUpdateFailed).Inc()
+		return fmt.Errorf("Failed to delete finalizer %s for provider config %s: %w", m.finalizerName, pcKey, err)
+	}
+	if removed {
+		m.recordEvent(pc, corev1.EventTypeNormal, ReasonFinalizerRemoved, "Removed finalizer %s", m.finalizerName)
+	}
+	m.metrics.stopDuration.Observe(m.clock.Since(stopTime).Seconds())
+	logger.Info("Stopped controllers for provider config", "duration", m.clock.Since(stopTime))
+	return nil
+}
+
+// CleanupControllersForProviderConfig tears down the controllers of a
+// ProviderConfig that no longer exists, e.g. because it was force-deleted or
+// its finalizer was removed by hand, and runs the cleanup hook once they
+// exited or the stop timeout expired. The call does not wait for them: while
+// they are stopping, the key is requeued to check again. It does nothing if no
+// controllers are tracked for key. The controller map entry is kept until the
+// hook succeeds, so that a failed cleanup is retried.
+func (m *manager) CleanupControllersForProviderConfig(ctx context.Context, key string) error {
+	m.admission.forget(key)
+	cs, exists := m.controllers.Get(key)
+	if !exists {
+		return nil
+	}
+	logger := klog.FromContext(ctx)
+	if tenantUID := cs.getTenantUID(); tenantUID != "" {
+		logger = logger.WithValues("tenantUID", tenantUID)
+		ctx = klog.NewContext(mtcontext.ContextWithTenantUID(ctx, tenantUID), logger)
+	}
+	if cs.running() {
+		logger.Info("Provider config was deleted without stopping its controllers; cleaning up")
+	}
+	stopping, timedOut, recheck := m.signalAndCheckStop(logger, cs, cs.Starters())
+	if len(stopping) > 0 {
+		m.requeueStopCheck(logger, key, stopping, recheck)
+		return nil
+	}
+	if len(timedOut) > 0 {
+		logger.Error(nil, "Controllers did not exit in time", "starters", timedOut, "stopTimeout", m.stopTimeout)
+	}
+	if err := m.runCleanupHook(ctx, key); err != nil {
+		return err
+	}
+	m.controllers.Delete(key)
+	logger.Info("Cleaned up controllers for deleted provider config")
+	return nil
+}
+
+// runCleanupHook runs the cleanup hook, if any, for the given ProviderConfig key.
+func (m *manager) runCleanupHook(ctx context.Context, key string) error {
+	if m.cleanupHook == nil {
+		return nil
+	}
+	if err := m.cleanupHook(ctx, key); err != nil {
+		return fmt.Errorf("cleanup hook failed for provider config %s: %w", key, err)
+	}
+	return nil
+}
+
+// ReleaseControllersForProviderConfig stops the controllers of the given
+// ProviderConfig without touching the finalizer or the status. It is used when
+// another replica takes over the ProviderConfig. The call does not wait for the
+// controllers to exit: it reports whether they did, or the stop timeout
+// expired, and otherwise requeues the ProviderConfig to check again.
+func (m *manager) ReleaseControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) bool {
+	pcKey := m.tenants.key(pc)
+	logger := klog.FromContext(ctx)
+	m.admission.forget(pcKey)
+	cs, exists := m.controllers.Get(pcKey)
+	if !exists {
+		return true
+	}
+	stopping, timedOut, recheck := m.signalAndCheckStop(logger, cs, cs.Starters())
+	if len(stopping) > 0 {
+		m.requeueStopCheck(logger, pcKey, stopping, recheck)
+		return false
+	}
+	if len(timedOut) > 0 {
+		logger.Error(nil, "Controllers did not exit in time", "starters", timedOut, "stopTimeout", m.stopTimeout)
+	}
+	m.controllers.Delete(pcKey)
+	logger.Info("Released controllers for provider config")
+	return true
+}
+
+// PauseControllersForProviderConfig stops the controllers of every starter for
+// a ProviderConfig annotated with PausedAnnotation. The call does not wait for
+// them to exit: while they are stopping, the ControllersRunning condition
+// reports ReasonControllersStopping and the ProviderConfig is requeued to check
+// again. The finalizer is kept so that deletion still goes through the
+// framework, and the controllers are started again by the first sync after the
+// annotation is removed.
+func (m *manager) PauseControllersForProviderConfig(ctx context.Context, pc *unstructured.Unstructured) error {
+	pcKey := m.tenants.key(pc)
+	logger := klog.FromContext(ctx)
+	m.admission.forget(pcKey)
+	pausedCondition := metav1.Condition{
+		Type:    ConditionPaused,
+		Status:  metav1.ConditionTrue,
+		Reason:  ReasonPauseRequested,
+		Message: fmt.Sprintf("The ProviderConfig is annotated with %s=true", PausedAnnotation),
+	}
+	if cs, exists := m.controllers.Get(pcKey); exists {
+		stopping, timedOut, recheck := m.signalAndCheckStop(logger, cs, cs.Starters())
+		if len(stopping) > 0 {
+			m.updateStatusConditions(ctx, pc, stoppingCondition(stopping), pausedCondition)
+			m.requeueStopCheck(logger, pcKey, stopping, recheck)
 			return nil
 		}
-		return fmt.Errorf("Failed to get latest ProviderConfig for finalizer removal: %w", err)
+		if len(timedOut) > 0 {
+			logger.Error(nil, "Controllers did not exit in time", "starters", timedOut, "stopTimeout", m.stopTimeout)
+		}
+		m.controllers.Delete(pcKey)
+		logger.Info("Paused controllers for provider config")
+		m.recordEvent(pc, corev1.EventTypeNormal, ReasonPauseRequested, "Stopped controllers because the ProviderConfig is annotated with %s=true", PausedAnnotation)
+	}
+	m.updateStatusConditions(ctx, pc,
+		metav1.Condition{
+			Type:    ConditionControllersRunning,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonPauseRequested,
+			Message: "Controllers for the ProviderConfig are paused",
+		},
+		pausedCondition,
+	)
+	return nil
+}
+
+// StopAllControllers stops the controllers of every ProviderConfig in parallel
+// and waits for them to exit, bounded by the stop timeout and by ctx.
+// Finalizers are left in place because the ProviderConfigs still exist;
+// whichever replica manages them next takes over. It returns an error naming
+// the ProviderConfigs whose controllers did not exit in time. It must not run
+// concurrently with other manager calls.
+func (m *manager) StopAllControllers(ctx context.Context) error {
+	keys := m.controllers.Keys()
+	// Signal every tenant before waiting for any of them, so that they all
+	// shut down at the same time.
+	for _, pcKey := range keys {
+		if cs, ok := m.controllers.Get(pcKey); ok {
+			m.signalAll(cs)
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+	for _, pcKey := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger := klog.FromContext(ctx).WithValues("providerConfig", pcKey)
+			exited, err := m.releaseControllers(klog.NewContext(ctx, logger), pcKey)
+			if err != nil {
+				logger.Error(err, "Failed to stop controllers")
+			}
+			if err == nil && exited {
+				return
+			}
+			m.metrics.stopFailures.WithLabelValues(stopReasonShutdownTimedOut).Inc()
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, pcKey)
+		}()
+	}
+	wg.Wait()
+	if len(failed) == 0 {
+		return nil
+	}
+	slices.Sort(failed)
+	return fmt.Errorf("controllers for %d provider config(s) did not stop in time: %s", len(failed), strings.Join(failed, ", "))
+}
+
+// signalAll signals the running controllers of every starter in cs to stop.
+func (m *manager) signalAll(cs *ControllerSet) {
+	for _, name := range cs.Starters() {
+		if sc := cs.controllersFor(name); sc.stopCh != nil {
+			m.signalStop(sc)
+		}
 	}
+}
 
-	finalizers := latestPC.GetFinalizers()
-	newFinalizers := slices.DeleteFunc(finalizers, func(f string) bool { return f == m.finalizerName })
-	if len(newFinalizers) != len(finalizers) {
-		latestPC.SetFinalizers(newFinalizers)
-		_, err := m.client.Resource(providerConfigGVR).Update(ctx, latestPC, metav1.UpdateOptions{})
+// releaseControllers stops the controllers of every starter for pcKey, waits
+// for them to exit and forgets them. It returns true if all of them exited
+// within the stop timeout. The entry is kept if waiting is interrupted.
+func (m *manager) releaseControllers(ctx context.Context, pcKey string) (bool, error) {
+	cs, ok := m.controllers.Get(pcKey)
+	if !ok {
+		return true, nil
+	}
+	m.signalAll(cs)
+	allExited := true
+	var errs []error
+	for _, name := range cs.Starters() {
+		exited, err := m.waitForControllersToExit(ctx, cs.controllersFor(name))
 		if err != nil {
-			return fmt.Errorf("Failed to delete finalizer %s for provider config %s: %w", m.finalizerName, pcKey, err)
+			errs = append(errs, fmt.Errorf("failed to stop controllers %s for provider config %s: %w", name, pcKey, err))
+			continue
+		}
+		if !exited {
+			allExited = false
+			klog.FromContext(ctx).Error(nil, "Controllers did not exit in time", "starter", name, "stopTimeout", m.stopTimeout)
 		}
 	}
-	klog.Info("Stopped controllers for provider config")
-	return nil
+	if len(errs) > 0 {
+		return false, errors.Join(errs...)
+	}
+	m.controllers.Delete(pcKey)
+	return allExited, nil
+}
+
+// startFailedConditions returns the conditions describing a failed start.
+func startFailedConditions(reason string, err error) []metav1.Condition {
+	return []metav1.Condition{
+		{
+			Type:    ConditionControllersRunning,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: "Not all controllers for the ProviderConfig are running",
+		},
+		{
+			Type:    ConditionStartFailed,
+			Status:  metav1.ConditionTrue,
+			Reason:  reason,
+			Message: err.Error(),
+		},
+	}
 }
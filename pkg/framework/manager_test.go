@@ -3,14 +3,26 @@ package framework
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+
+	mtcontext "github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework/mtcontext"
 )
 
 func createProviderConfigInClient(ctx context.Context, client dynamic.Interface, pc *unstructured.Unstructured) error {
@@ -161,6 +173,54 @@ func TestManagerStartAddsFinalizerBeforeControllerStarts(t *testing.T) {
 	}
 }
 
+// TestManagerStartReaddsRemovedFinalizer verifies that a sync of a
+// ProviderConfig whose finalizer was removed while its controllers are running
+// re-adds the finalizer without restarting the controllers.
+func TestManagerStartReaddsRemovedFinalizer(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	mockStarter := newMockControllerStarter()
+	manager := newManager(dynamicClient, "test-finalizer", mockStarter)
+
+	pc := createTestProviderConfig("test-pc")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create test ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if got := manager.TrackedProviderConfigs(); !got["test-pc"] {
+		t.Errorf("TrackedProviderConfigs() = %v, want test-pc running", got)
+	}
+
+	pc, err := providerConfigFromClient(ctx, dynamicClient, "test-pc")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	pc.SetFinalizers(nil)
+	pc, err = dynamicClient.Resource(providerConfigGVR).Update(ctx, pc, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to remove finalizer: %v", err)
+	}
+	if manager.HasFinalizer(pc) {
+		t.Fatalf("Expected finalizer to be removed")
+	}
+
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Second start failed: %v", err)
+	}
+	updatedPC, err := providerConfigFromClient(ctx, dynamicClient, "test-pc")
+	if err != nil {
+		t.Fatalf("Failed to get updated ProviderConfig: %v", err)
+	}
+	if !hasFinalizer(updatedPC, "test-finalizer") {
+		t.Errorf("Expected finalizer to be re-added")
+	}
+	if got := mockStarter.getStartCallCount(); got != 1 {
+		t.Errorf("Expected 1 start call, got %d", got)
+	}
+}
+
 // TestManagerStartFailureRollsBackFinalizer verifies that if controller startup fails,
 // the finalizer is rolled back.
 func TestManagerStartFailureRollsBackFinalizer(t *testing.T) {
@@ -517,3 +577,1595 @@ func TestManagerStartReturnsErrorOnNilChannel(t *testing.T) {
 		t.Fatal("Expected StartControllersForProviderConfig to fail when StartController returns nil channel, but it succeeded")
 	}
 }
+
+// conditionFromClient returns the status condition of the given type stored on the ProviderConfig.
+func conditionFromClient(ctx context.Context, t *testing.T, client dynamic.Interface, name, conditionType string) *metav1.Condition {
+	t.Helper()
+	pc, err := providerConfigFromClient(ctx, client, name)
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig %s: %v", name, err)
+	}
+	conditions, err := conditionsFromUnstructured(pc)
+	if err != nil {
+		t.Fatalf("Failed to read conditions of ProviderConfig %s: %v", name, err)
+	}
+	return meta.FindStatusCondition(conditions, conditionType)
+}
+
+// TestManagerStatusConditionsLifecycle verifies that start and stop are reflected
+// in the ProviderConfig status conditions.
+func TestManagerStatusConditionsLifecycle(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	mockStarter := newMockControllerStarter()
+
+	manager := newManager(
+		dynamicClient,
+		"test-finalizer",
+		mockStarter,
+	)
+
+	pc := createTestProviderConfig("test-pc")
+	pc.SetGeneration(2)
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create test ProviderConfig: %v", err)
+	}
+
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	running := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning)
+	if running == nil || running.Status != metav1.ConditionTrue || running.Reason != ReasonControllersStarted {
+		t.Fatalf("Expected %s=True after start, got %+v", ConditionControllersRunning, running)
+	}
+	if running.ObservedGeneration != 2 {
+		t.Errorf("Expected observedGeneration 2, got %d", running.ObservedGeneration)
+	}
+
+	updatedPC, err := providerConfigFromClient(ctx, dynamicClient, pc.GetName())
+	if err != nil {
+		t.Fatalf("Failed to get updated ProviderConfig: %v", err)
+	}
+	if err := manager.StopControllersForProviderConfig(ctx, updatedPC); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	terminating := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionTerminating)
+	if terminating == nil || terminating.Status != metav1.ConditionTrue {
+		t.Errorf("Expected %s=True after stop, got %+v", ConditionTerminating, terminating)
+	}
+	running = conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning)
+	if running == nil || running.Status != metav1.ConditionFalse || running.Reason != ReasonControllersStopped {
+		t.Errorf("Expected %s=False after stop, got %+v", ConditionControllersRunning, running)
+	}
+}
+
+// TestManagerStatusConditionsStartFailure verifies that a failed start is reported
+// in the StartFailed condition with the starter's error.
+func TestManagerStatusConditionsStartFailure(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	mockStarter := newMockControllerStarter()
+	mockStarter.shouldFailStart = true
+
+	manager := newManager(
+		dynamicClient,
+		"test-finalizer",
+		mockStarter,
+	)
+
+	pc := createTestProviderConfig("test-pc")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create test ProviderConfig: %v", err)
+	}
+
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err == nil {
+		t.Fatal("Expected start to fail, but it succeeded")
+	}
+
+	startFailed := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionStartFailed)
+	if startFailed == nil || startFailed.Status != metav1.ConditionTrue || startFailed.Reason != ReasonControllerStartFailed {
+		t.Fatalf("Expected %s=True after failed start, got %+v", ConditionStartFailed, startFailed)
+	}
+	if !strings.Contains(startFailed.Message, "mock start failure") {
+		t.Errorf("Expected %s message to contain the starter error, got %q", ConditionStartFailed, startFailed.Message)
+	}
+	running := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning)
+	if running == nil || running.Status != metav1.ConditionFalse {
+		t.Errorf("Expected %s=False after failed start, got %+v", ConditionControllersRunning, running)
+	}
+}
+
+// TestManagerStatusConditionsRetryOnConflict verifies that status updates that
+// conflict with a concurrent change are retried instead of dropped.
+func TestManagerStatusConditionsRetryOnConflict(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	var statusUpdates atomic.Int32
+	dynamicClient.PrependReactor("update", testProviderConfigGVR.Resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "status" || statusUpdates.Add(1) > 2 {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewConflict(testProviderConfigGVR.GroupResource(), "test-pc", fmt.Errorf("object was modified"))
+	})
+	manager := newManager(dynamicClient, "test-finalizer", newMockControllerStarter())
+
+	pc := createTestProviderConfig("test-pc")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create test ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if got := statusUpdates.Load(); got < 3 {
+		t.Errorf("Expected the conflicting status update to be retried, got %d attempts", got)
+	}
+	running := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning)
+	if running == nil || running.Status != metav1.ConditionTrue {
+		t.Errorf("Expected %s=True despite conflicts, got %+v", ConditionControllersRunning, running)
+	}
+}
+
+// handleControllerStarter is a HandleControllerStarter whose controllers exit
+// only when the test closes the returned done channel.
+type handleControllerStarter struct {
+	*mockControllerStarter
+	stopChs map[string]chan struct{}
+	doneChs map[string]chan struct{}
+}
+
+func newHandleControllerStarter() *handleControllerStarter {
+	return &handleControllerStarter{
+		mockControllerStarter: newMockControllerStarter(),
+		stopChs:               make(map[string]chan struct{}),
+		doneChs:               make(map[string]chan struct{}),
+	}
+}
+
+func (h *handleControllerStarter) StartControllerWithHandle(pc *unstructured.Unstructured) (*ControllerHandle, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.startCalls++
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	h.stopChs[pc.GetName()] = stopCh
+	h.doneChs[pc.GetName()] = doneCh
+	return &ControllerHandle{StopCh: stopCh, Done: doneCh}, nil
+}
+
+func (h *handleControllerStarter) channels(name string) (chan struct{}, chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stopChs[name], h.doneChs[name]
+}
+
+// TestManagerStopWaitsForControllersToExit verifies that the finalizer is kept
+// until a HandleControllerStarter reports that its controllers have exited,
+// and that the stop requeues the ProviderConfig instead of waiting for them.
+func TestManagerStopWaitsForControllersToExit(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newHandleControllerStarter()
+	recorder := &requeueRecorder{}
+
+	finalizerName := "test-finalizer"
+	manager := newManager(
+		dynamicClient,
+		finalizerName,
+		starter,
+		WithStopTimeout(time.Minute),
+	)
+	manager.requeueAfter = recorder.requeueAfter
+
+	pc := createTestProviderConfig("test-pc")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create test ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if starter.getStartCallCount() != 1 {
+		t.Fatalf("Expected StartControllerWithHandle to be called once, got %d", starter.getStartCallCount())
+	}
+
+	if err := manager.StopControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	stopCh, doneCh := starter.channels(pc.GetName())
+	if !isClosed(stopCh) {
+		t.Fatal("Expected the controllers to be signaled to stop")
+	}
+	pcAfter, err := providerConfigFromClient(ctx, dynamicClient, pc.GetName())
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if !hasFinalizer(pcAfter, finalizerName) {
+		t.Fatal("Finalizer was removed although the controllers have not exited")
+	}
+	running := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning)
+	if running == nil || running.Reason != ReasonControllersStopping {
+		t.Errorf("Expected %s reason %s, got %+v", ConditionControllersRunning, ReasonControllersStopping, running)
+	}
+	if got := recorder.get(); len(got) != 1 || got[0] != stopCheckInterval {
+		t.Errorf("Expected the stop to be checked again after %v, got %v", stopCheckInterval, got)
+	}
+
+	close(doneCh)
+	if err := manager.StopControllersForProviderConfig(ctx, pcAfter); err != nil {
+		t.Fatalf("Second stop failed: %v", err)
+	}
+	finalPC, err := providerConfigFromClient(ctx, dynamicClient, pc.GetName())
+	if err != nil {
+		t.Fatalf("Failed to get final ProviderConfig: %v", err)
+	}
+	if hasFinalizer(finalPC, finalizerName) {
+		t.Errorf("Finalizer %s was not removed after controllers exited", finalizerName)
+	}
+	running = conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning)
+	if running == nil || running.Reason != ReasonControllersStopped {
+		t.Errorf("Expected %s reason %s, got %+v", ConditionControllersRunning, ReasonControllersStopped, running)
+	}
+	if _, exists := manager.controllers.Get(pc.GetName()); exists {
+		t.Error("Controller map entry should have been removed after the controllers exited")
+	}
+}
+
+// TestManagerStopTimeoutRemovesFinalizer verifies that the finalizer is removed
+// once the stop timeout expires and that the timeout is reported in status.
+func TestManagerStopTimeoutRemovesFinalizer(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newHandleControllerStarter()
+	recorder := &requeueRecorder{}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+
+	finalizerName := "test-finalizer"
+	manager := newManager(
+		dynamicClient,
+		finalizerName,
+		starter,
+		WithStopTimeout(500*time.Millisecond),
+		WithClock(fakeClock),
+	)
+	manager.requeueAfter = recorder.requeueAfter
+
+	pc := createTestProviderConfig("test-pc")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create test ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// The done channel is never closed.
+	if err := manager.StopControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if got := recorder.get(); len(got) != 1 || got[0] != 500*time.Millisecond {
+		t.Errorf("Expected the stop to be checked again when the stop timeout expires, got %v", got)
+	}
+	fakeClock.Step(500 * time.Millisecond)
+	if err := manager.StopControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Second stop failed: %v", err)
+	}
+
+	finalPC, err := providerConfigFromClient(ctx, dynamicClient, pc.GetName())
+	if err != nil {
+		t.Fatalf("Failed to get final ProviderConfig: %v", err)
+	}
+	if hasFinalizer(finalPC, finalizerName) {
+		t.Errorf("Finalizer %s was not removed after the stop timeout", finalizerName)
+	}
+	running := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning)
+	if running == nil || running.Status != metav1.ConditionUnknown || running.Reason != ReasonStopTimedOut {
+		t.Errorf("Expected %s=Unknown with reason %s, got %+v", ConditionControllersRunning, ReasonStopTimedOut, running)
+	}
+}
+
+// updatingControllerStarter is a ControllerUpdater that records the updates it receives.
+type updatingControllerStarter struct {
+	*mockControllerStarter
+	updates []*unstructured.Unstructured
+}
+
+func (u *updatingControllerStarter) UpdateController(pc *unstructured.Unstructured) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.updates = append(u.updates, pc)
+	return nil
+}
+
+// withProjectID returns a copy of pc with a different spec and a bumped generation.
+func withProjectID(pc *unstructured.Unstructured, projectID string) *unstructured.Unstructured {
+	updated := pc.DeepCopy()
+	updated.Object["spec"] = map[string]any{"projectID": projectID}
+	updated.SetGeneration(pc.GetGeneration() + 1)
+	return updated
+}
+
+// TestManagerRestartsControllersOnSpecChange verifies that the default policy stops
+// the running controllers and starts new ones when the spec changes.
+func TestManagerRestartsControllersOnSpecChange(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newHandleControllerStarter()
+
+	manager := newManager(
+		dynamicClient,
+		"test-finalizer",
+		starter,
+		WithStopTimeout(time.Minute),
+	)
+
+	pc := createTestProviderConfig("test-pc")
+	pc.SetGeneration(1)
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create test ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	oldStopCh, oldDoneCh := starter.channels(pc.GetName())
+	updated := withProjectID(pc, "other-project")
+	if err := manager.StartControllersForProviderConfig(ctx, updated); err != nil {
+		t.Fatalf("Start after spec change failed: %v", err)
+	}
+	if !isClosed(oldStopCh) {
+		t.Fatal("Expected the old controllers to be signaled to stop")
+	}
+	if starter.getStartCallCount() != 1 {
+		t.Fatalf("New controllers were started before the old ones exited, got %d starts", starter.getStartCallCount())
+	}
+
+	// The requeued sync starts the new controllers once the old ones exited.
+	close(oldDoneCh)
+	if err := manager.StartControllersForProviderConfig(ctx, updated); err != nil {
+		t.Fatalf("Start after the old controllers exited failed: %v", err)
+	}
+	if starter.getStartCallCount() != 2 {
+		t.Fatalf("Expected controllers to be started twice, got %d", starter.getStartCallCount())
+	}
+	cs, _ := manager.controllers.Get(pc.GetName())
+	if sc := cs.controllersFor(DefaultControllerStarterName); sc.generation != 2 {
+		t.Errorf("Expected recorded generation 2, got %d", sc.generation)
+	}
+	running := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning)
+	if running == nil || running.ObservedGeneration != 2 {
+		t.Errorf("Expected %s to observe generation 2, got %+v", ConditionControllersRunning, running)
+	}
+
+	// A generation bump without a spec change does not restart the controllers.
+	bumped := updated.DeepCopy()
+	bumped.SetGeneration(3)
+	if err := manager.StartControllersForProviderConfig(ctx, bumped); err != nil {
+		t.Fatalf("Start after generation bump failed: %v", err)
+	}
+	if starter.getStartCallCount() != 2 {
+		t.Errorf("Expected no restart for an unchanged spec, got %d starts", starter.getStartCallCount())
+	}
+}
+
+// TestManagerSpecChangePolicies verifies the update and ignore policies.
+func TestManagerSpecChangePolicies(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		policy      SpecChangePolicy
+		wantStarts  int
+		wantUpdates int
+	}{
+		{
+			desc:        "update calls the hook",
+			policy:      SpecChangePolicyUpdate,
+			wantStarts:  1,
+			wantUpdates: 1,
+		},
+		{
+			desc:        "ignore leaves controllers untouched",
+			policy:      SpecChangePolicyIgnore,
+			wantStarts:  1,
+			wantUpdates: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := context.Background()
+			dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+			starter := &updatingControllerStarter{mockControllerStarter: newMockControllerStarter()}
+
+			manager := newManager(
+				dynamicClient,
+				"test-finalizer",
+				starter,
+				WithSpecChangePolicy(tc.policy),
+			)
+
+			pc := createTestProviderConfig("test-pc")
+			if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+				t.Fatalf("Failed to create test ProviderConfig: %v", err)
+			}
+			if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			if err := manager.StartControllersForProviderConfig(ctx, withProjectID(pc, "other-project")); err != nil {
+				t.Fatalf("Start after spec change failed: %v", err)
+			}
+
+			if got := starter.getStartCallCount(); got != tc.wantStarts {
+				t.Errorf("Expected %d start calls, got %d", tc.wantStarts, got)
+			}
+			if got := len(starter.updates); got != tc.wantUpdates {
+				t.Errorf("Expected %d update calls, got %d", tc.wantUpdates, got)
+			}
+		})
+	}
+}
+
+// requeueRecorder records the delays passed to manager.requeueAfter.
+type requeueRecorder struct {
+	mu     sync.Mutex
+	delays []time.Duration
+}
+
+func (r *requeueRecorder) requeueAfter(_ string, delay time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.delays = append(r.delays, delay)
+}
+
+func (r *requeueRecorder) get() []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Duration(nil), r.delays...)
+}
+
+// TestManagerRestartsCrashedControllersWithBackoff verifies that controllers that exit
+// without being asked to stop are restarted with exponential backoff.
+func TestManagerRestartsCrashedControllersWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newHandleControllerStarter()
+	recorder := &requeueRecorder{}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+
+	initialBackoff := 20 * time.Millisecond
+	manager := newManager(
+		dynamicClient,
+		"test-finalizer",
+		starter,
+		WithRestartBackoff(initialBackoff, time.Minute),
+		WithClock(fakeClock),
+	)
+	manager.requeueAfter = recorder.requeueAfter
+
+	pc := createTestProviderConfig("test-pc")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create test ProviderConfig: %v", err)
+	}
+
+	for crash := 1; crash <= 2; crash++ {
+		if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		if got := starter.getStartCallCount(); got != crash {
+			t.Fatalf("Expected %d start calls, got %d", crash, got)
+		}
+
+		// Crash the controllers.
+		_, doneCh := starter.channels(pc.GetName())
+		close(doneCh)
+		if err := wait.PollImmediate(5*time.Millisecond, time.Second, func() (bool, error) {
+			return len(recorder.get()) == 2*crash-1, nil
+		}); err != nil {
+			t.Fatalf("Expected the crash to requeue the ProviderConfig: %v", err)
+		}
+
+		// The next sync observes the crash and schedules a delayed restart.
+		if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+			t.Fatalf("Start after crash failed: %v", err)
+		}
+		if got := starter.getStartCallCount(); got != crash {
+			t.Fatalf("Expected no restart during backoff, got %d start calls", got)
+		}
+		delays := recorder.get()
+		wantDelay := initialBackoff << (crash - 1)
+		if got := delays[len(delays)-1]; got != wantDelay {
+			t.Errorf("Expected restart backoff %v after crash %d, got %v", wantDelay, crash, got)
+		}
+		running := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning)
+		if running == nil || running.Reason != ReasonControllersExited {
+			t.Errorf("Expected %s reason %s after crash, got %+v", ConditionControllersRunning, ReasonControllersExited, running)
+		}
+
+		fakeClock.Step(wantDelay)
+	}
+
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start after backoff failed: %v", err)
+	}
+	if got := starter.getStartCallCount(); got != 3 {
+		t.Fatalf("Expected controllers to be restarted after backoff, got %d start calls", got)
+	}
+	cs, _ := manager.controllers.Get(pc.GetName())
+	if got := cs.Restarts(DefaultControllerStarterName); got != 2 {
+		t.Errorf("Expected restart count 2, got %d", got)
+	}
+
+	// A requested stop is not reported as a crash: it is only requeued to
+	// check whether the controllers exited.
+	_, doneCh := starter.channels(pc.GetName())
+	if err := manager.StopControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	close(doneCh)
+	if err := manager.StopControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Second stop failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := recorder.get(); len(got) != 5 || got[4] != stopCheckInterval {
+		t.Errorf("Expected only the stop check to be requeued for a requested stop, got %v", got)
+	}
+}
+
+// TestManagerNamedStartersSelectedByLabels verifies that named starters run only for
+// ProviderConfigs matching their selector and are stopped when the labels stop matching.
+func TestManagerNamedStartersSelectedByLabels(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	defaultStarter := newMockControllerStarter()
+	lbStarter := newHandleControllerStarter()
+	recorder := &requeueRecorder{}
+
+	manager := newManager(
+		dynamicClient,
+		"test-finalizer",
+		defaultStarter,
+		WithNamedControllerStarter("lb", lbStarter, labels.SelectorFromSet(labels.Set{"lb": "enabled"})),
+		WithStopTimeout(time.Minute),
+	)
+	manager.requeueAfter = recorder.requeueAfter
+
+	plain := createTestProviderConfig("plain")
+	selected := createTestProviderConfig("selected")
+	selected.SetLabels(map[string]string{"lb": "enabled"})
+	for _, pc := range []*unstructured.Unstructured{plain, selected} {
+		if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+			t.Fatalf("Failed to create ProviderConfig %s: %v", pc.GetName(), err)
+		}
+		if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+			t.Fatalf("Start failed for %s: %v", pc.GetName(), err)
+		}
+	}
+
+	if got := defaultStarter.getStartCallCount(); got != 2 {
+		t.Errorf("Expected the default starter to run for both ProviderConfigs, got %d starts", got)
+	}
+	if got := lbStarter.getStartCallCount(); got != 1 {
+		t.Errorf("Expected the lb starter to run only for the labelled ProviderConfig, got %d starts", got)
+	}
+	cs, _ := manager.controllers.Get(selected.GetName())
+	if got, want := cs.Starters(), []string{DefaultControllerStarterName, "lb"}; !slices.Equal(got, want) {
+		t.Errorf("Starters() = %v, want %v", got, want)
+	}
+
+	// Removing the label stops only the lb controllers, without waiting for
+	// them to exit.
+	stopCh, doneCh := lbStarter.channels(selected.GetName())
+	unlabelled := selected.DeepCopy()
+	unlabelled.SetLabels(nil)
+	if err := manager.StartControllersForProviderConfig(ctx, unlabelled); err != nil {
+		t.Fatalf("Start after label removal failed: %v", err)
+	}
+	if !isClosed(stopCh) {
+		t.Error("Expected the lb controllers to be signaled to stop after the label was removed")
+	}
+	if got, want := cs.Starters(), []string{DefaultControllerStarterName, "lb"}; !slices.Equal(got, want) {
+		t.Errorf("Starters() while the lb controllers are stopping = %v, want %v", got, want)
+	}
+	if got := recorder.get(); len(got) != 1 || got[0] != stopCheckInterval {
+		t.Errorf("Expected the stop to be checked again after %v, got %v", stopCheckInterval, got)
+	}
+
+	close(doneCh)
+	if err := manager.StartControllersForProviderConfig(ctx, unlabelled); err != nil {
+		t.Fatalf("Start after the lb controllers exited failed: %v", err)
+	}
+	if got, want := cs.Starters(), []string{DefaultControllerStarterName}; !slices.Equal(got, want) {
+		t.Errorf("Starters() = %v, want %v", got, want)
+	}
+	if got := defaultStarter.getStartCallCount(); got != 2 {
+		t.Errorf("Expected the default controllers to keep running, got %d starts", got)
+	}
+}
+
+// TestManagerNoEnabledStarterIsNotTracked verifies that a ProviderConfig for
+// which no starter is enabled is not tracked as a tenant.
+func TestManagerNoEnabledStarterIsNotTracked(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	lbStarter := newMockControllerStarter()
+	manager := newManager(dynamicClient, "test-finalizer", nil,
+		WithNamedControllerStarter("lb", lbStarter, labels.SelectorFromSet(labels.Set{"lb": "enabled"})),
+	)
+
+	pc := createTestProviderConfig("pc-unselected")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if got := lbStarter.getStartCallCount(); got != 0 {
+		t.Errorf("Expected no starts, got %d", got)
+	}
+	if tracked := manager.TrackedProviderConfigs(); len(tracked) != 0 {
+		t.Errorf("Expected no tracked ProviderConfigs, got %v", tracked)
+	}
+	if tenants := manager.DebugTenants(); len(tenants) != 0 {
+		t.Errorf("Expected no tenants in the debug state, got %v", tenants)
+	}
+}
+
+// TestManagerNamedStarterFailureKeepsOthersRunning verifies that a failing starter
+// does not prevent the other starters from running or roll back the finalizer.
+func TestManagerNamedStarterFailureKeepsOthersRunning(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	defaultStarter := newMockControllerStarter()
+	failingStarter := newMockControllerStarter()
+	failingStarter.shouldFailStart = true
+
+	finalizerName := "test-finalizer"
+	manager := newManager(
+		dynamicClient,
+		finalizerName,
+		defaultStarter,
+		WithNamedControllerStarter("ipam", failingStarter, nil),
+	)
+
+	pc := createTestProviderConfig("test-pc")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create test ProviderConfig: %v", err)
+	}
+
+	err := manager.StartControllersForProviderConfig(ctx, pc)
+	if err == nil || !strings.Contains(err.Error(), "ipam") {
+		t.Fatalf("Expected start to fail naming the ipam starter, got %v", err)
+	}
+
+	updatedPC, err := providerConfigFromClient(ctx, dynamicClient, pc.GetName())
+	if err != nil {
+		t.Fatalf("Failed to get updated ProviderConfig: %v", err)
+	}
+	if !hasFinalizer(updatedPC, finalizerName) {
+		t.Error("Finalizer was rolled back although the default controllers are running")
+	}
+
+	// A retry only starts the failed starter.
+	failingStarter.mu.Lock()
+	failingStarter.shouldFailStart = false
+	failingStarter.mu.Unlock()
+	if err := manager.StartControllersForProviderConfig(ctx, updatedPC); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if got := defaultStarter.getStartCallCount(); got != 1 {
+		t.Errorf("Expected the default starter to be started once, got %d", got)
+	}
+	if got := failingStarter.getStartCallCount(); got != 2 {
+		t.Errorf("Expected the ipam starter to be retried, got %d starts", got)
+	}
+}
+
+// TestNamedControllerStarterValidation verifies that invalid named starters
+// are rejected and not registered, while valid ones are kept.
+func TestNamedControllerStarterValidation(t *testing.T) {
+	valid := WithNamedControllerStarter("ipam", newMockControllerStarter(), nil)
+	testCases := []struct {
+		desc string
+		opt  Option
+	}{
+		{"empty name", WithNamedControllerStarter("", newMockControllerStarter(), nil)},
+		{"default name", WithNamedControllerStarter(DefaultControllerStarterName, newMockControllerStarter(), nil)},
+		{"nil starter", WithNamedControllerStarter("lb", nil, nil)},
+		{"duplicate name", WithNamedControllerStarter("ipam", newMockControllerStarter(), nil)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			o := newOptions(valid, tc.opt)
+			if err := o.validate(); err == nil {
+				t.Error("Expected validate to return an error")
+			}
+			if len(o.namedStarters) != 1 || o.namedStarters[0].name != "ipam" {
+				t.Errorf("Expected only the ipam starter to be registered, got %+v", o.namedStarters)
+			}
+		})
+	}
+	if err := newOptions(valid).validate(); err != nil {
+		t.Errorf("Expected a valid named starter to be accepted, got %v", err)
+	}
+}
+
+// TestManagerStopAllControllersKeepsFinalizers verifies that StopAllControllers stops
+// every tenant without removing finalizers.
+func TestManagerStopAllControllersKeepsFinalizers(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newHandleControllerStarter()
+
+	finalizerName := "test-finalizer"
+	manager := newManager(
+		dynamicClient,
+		finalizerName,
+		starter,
+	)
+
+	names := []string{"pc-1", "pc-2"}
+	for _, name := range names {
+		pc := createTestProviderConfig(name)
+		if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+			t.Fatalf("Failed to create ProviderConfig %s: %v", name, err)
+		}
+		if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+			t.Fatalf("Start failed for %s: %v", name, err)
+		}
+		stopCh, doneCh := starter.channels(name)
+		go func() {
+			<-stopCh
+			close(doneCh)
+		}()
+	}
+
+	if err := manager.StopAllControllers(ctx); err != nil {
+		t.Fatalf("StopAllControllers failed: %v", err)
+	}
+
+	for _, name := range names {
+		if _, doneCh := starter.channels(name); !isClosed(doneCh) {
+			t.Errorf("Expected controllers for %s to have exited", name)
+		}
+		if _, exists := manager.controllers.Get(name); exists {
+			t.Errorf("Expected controller map entry for %s to be removed", name)
+		}
+		pc, err := providerConfigFromClient(ctx, dynamicClient, name)
+		if err != nil {
+			t.Fatalf("Failed to get ProviderConfig %s: %v", name, err)
+		}
+		if !hasFinalizer(pc, finalizerName) {
+			t.Errorf("Expected finalizer on %s to be kept", name)
+		}
+	}
+}
+
+// TestManagerStopAllControllersReportsStuckTenants verifies that StopAllControllers
+// signals every tenant before waiting for any of them, and reports the tenants
+// whose controllers did not exit before the deadline.
+func TestManagerStopAllControllersReportsStuckTenants(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newHandleControllerStarter()
+
+	finalizerName := "test-finalizer"
+	manager := newManager(
+		dynamicClient,
+		finalizerName,
+		starter,
+		WithStopTimeout(time.Hour),
+	)
+
+	names := []string{"pc-stuck", "pc-stopping-1", "pc-stopping-2"}
+	for _, name := range names {
+		pc := createTestProviderConfig(name)
+		if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+			t.Fatalf("Failed to create ProviderConfig %s: %v", name, err)
+		}
+		if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+			t.Fatalf("Start failed for %s: %v", name, err)
+		}
+	}
+	// Each stopping tenant only exits once both were asked to stop, which
+	// requires the stop signals to be sent in parallel.
+	stop1, done1 := starter.channels("pc-stopping-1")
+	stop2, done2 := starter.channels("pc-stopping-2")
+	for _, done := range []chan struct{}{done1, done2} {
+		go func() {
+			<-stop1
+			<-stop2
+			close(done)
+		}()
+	}
+
+	deadline, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	err := manager.StopAllControllers(deadline)
+	if err == nil || !strings.Contains(err.Error(), "pc-stuck") || strings.Contains(err.Error(), "pc-stopping") {
+		t.Fatalf("Expected StopAllControllers to report only pc-stuck, got %v", err)
+	}
+
+	if stuckStop, _ := starter.channels("pc-stuck"); !isClosed(stuckStop) {
+		t.Error("Expected pc-stuck to be signaled to stop")
+	}
+	for _, name := range []string{"pc-stopping-1", "pc-stopping-2"} {
+		if _, exists := manager.controllers.Get(name); exists {
+			t.Errorf("Expected controller map entry for %s to be removed", name)
+		}
+	}
+	for _, name := range names {
+		pc, err := providerConfigFromClient(ctx, dynamicClient, name)
+		if err != nil {
+			t.Fatalf("Failed to get ProviderConfig %s: %v", name, err)
+		}
+		if !hasFinalizer(pc, finalizerName) {
+			t.Errorf("Expected finalizer on %s to be kept", name)
+		}
+	}
+}
+
+// isClosed reports whether ch is closed.
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// TestManagerReleaseControllersKeepsFinalizer verifies that releasing a ProviderConfig
+// stops its controllers without removing the finalizer, and that it can be started again.
+func TestManagerReleaseControllersKeepsFinalizer(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newMockControllerStarter()
+
+	finalizerName := "test-finalizer"
+	manager := newManager(
+		dynamicClient,
+		finalizerName,
+		starter,
+	)
+
+	pc := createTestProviderConfig("pc-release")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if !manager.ReleaseControllersForProviderConfig(ctx, pc) {
+		t.Fatal("Expected the controllers to be released")
+	}
+	if _, exists := manager.controllers.Get(pc.GetName()); exists {
+		t.Error("Expected controller map entry to be removed after release")
+	}
+	latest, err := providerConfigFromClient(ctx, dynamicClient, pc.GetName())
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if !hasFinalizer(latest, finalizerName) {
+		t.Error("Expected finalizer to be kept after release")
+	}
+
+	if !manager.ReleaseControllersForProviderConfig(ctx, pc) {
+		t.Error("Releasing a ProviderConfig without controllers should be a no-op")
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, latest); err != nil {
+		t.Fatalf("Restart after release failed: %v", err)
+	}
+	if got := starter.getStartCallCount(); got != 2 {
+		t.Errorf("Expected 2 start calls, got %d", got)
+	}
+}
+
+// TestManagerReleaseDoesNotWaitForControllersToExit verifies that releasing a
+// ProviderConfig whose controllers are slow to exit does not block the worker,
+// and that the release completes once a later sync observes them exit.
+func TestManagerReleaseDoesNotWaitForControllersToExit(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newHandleControllerStarter()
+	recorder := &requeueRecorder{}
+	manager := newManager(dynamicClient, "test-finalizer", starter, WithStopTimeout(time.Minute))
+	manager.requeueAfter = recorder.requeueAfter
+
+	pc := createTestProviderConfig("pc-release-slow")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if manager.ReleaseControllersForProviderConfig(ctx, pc) {
+		t.Fatal("Did not expect the controllers to be released before they exited")
+	}
+	stopCh, doneCh := starter.channels(pc.GetName())
+	if !isClosed(stopCh) {
+		t.Fatal("Expected the controllers to be signaled to stop")
+	}
+	if _, exists := manager.controllers.Get(pc.GetName()); !exists {
+		t.Error("Expected the controller map entry to be kept while the controllers are stopping")
+	}
+	if got := recorder.get(); len(got) != 1 || got[0] != stopCheckInterval {
+		t.Errorf("Expected the release to be checked again after %v, got %v", stopCheckInterval, got)
+	}
+
+	close(doneCh)
+	if !manager.ReleaseControllersForProviderConfig(ctx, pc) {
+		t.Fatal("Expected the controllers to be released once they exited")
+	}
+	if _, exists := manager.controllers.Get(pc.GetName()); exists {
+		t.Error("Expected the controller map entry to be removed once the controllers exited")
+	}
+}
+
+// TestManagerPauseKeepsFinalizer verifies that pausing a ProviderConfig stops
+// its controllers without removing the finalizer, reports the pause in its
+// status, and that starting it again reports the resume.
+func TestManagerPauseKeepsFinalizer(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newMockControllerStarter()
+
+	finalizerName := "test-finalizer"
+	manager := newManager(dynamicClient, finalizerName, starter)
+
+	pc := createTestProviderConfig("pc-pause")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	pc.SetAnnotations(map[string]string{PausedAnnotation: "true"})
+	if err := manager.PauseControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+	if _, exists := manager.controllers.Get(pc.GetName()); exists {
+		t.Error("Expected controller map entry to be removed after pause")
+	}
+	latest, err := providerConfigFromClient(ctx, dynamicClient, pc.GetName())
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if !hasFinalizer(latest, finalizerName) {
+		t.Error("Expected finalizer to be kept after pause")
+	}
+	if cond := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionPaused); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("Expected Paused condition to be True after pause, got %+v", cond)
+	}
+	if cond := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning); cond == nil || cond.Reason != ReasonPauseRequested {
+		t.Errorf("Expected ControllersRunning condition with reason %s, got %+v", ReasonPauseRequested, cond)
+	}
+
+	if err := manager.StartControllersForProviderConfig(ctx, latest); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if got := starter.getStartCallCount(); got != 2 {
+		t.Errorf("Expected 2 start calls, got %d", got)
+	}
+	if cond := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionPaused); cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != ReasonResumed {
+		t.Errorf("Expected Paused condition to be False with reason %s after resume, got %+v", ReasonResumed, cond)
+	}
+}
+
+// TestManagerPauseDoesNotWaitForControllersToExit verifies that pausing a
+// ProviderConfig whose controllers are slow to exit does not block the worker,
+// and that the pause completes once a later sync observes them exit.
+func TestManagerPauseDoesNotWaitForControllersToExit(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newHandleControllerStarter()
+	recorder := &requeueRecorder{}
+	manager := newManager(dynamicClient, "test-finalizer", starter, WithStopTimeout(time.Minute))
+	manager.requeueAfter = recorder.requeueAfter
+
+	pc := createTestProviderConfig("pc-pause-slow")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	pc.SetAnnotations(map[string]string{PausedAnnotation: "true"})
+	if err := manager.PauseControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+	stopCh, doneCh := starter.channels(pc.GetName())
+	if !isClosed(stopCh) {
+		t.Fatal("Expected the controllers to be signaled to stop")
+	}
+	if _, exists := manager.controllers.Get(pc.GetName()); !exists {
+		t.Error("Expected the controller map entry to be kept while the controllers are stopping")
+	}
+	if cond := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning); cond == nil || cond.Reason != ReasonControllersStopping {
+		t.Errorf("Expected ControllersRunning condition with reason %s, got %+v", ReasonControllersStopping, cond)
+	}
+	if got := recorder.get(); len(got) != 1 || got[0] != stopCheckInterval {
+		t.Errorf("Expected the pause to be checked again after %v, got %v", stopCheckInterval, got)
+	}
+
+	close(doneCh)
+	if err := manager.PauseControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Second pause failed: %v", err)
+	}
+	if _, exists := manager.controllers.Get(pc.GetName()); exists {
+		t.Error("Expected the controller map entry to be removed once the controllers exited")
+	}
+	if cond := conditionFromClient(ctx, t, dynamicClient, pc.GetName(), ConditionControllersRunning); cond == nil || cond.Reason != ReasonPauseRequested {
+		t.Errorf("Expected ControllersRunning condition with reason %s, got %+v", ReasonPauseRequested, cond)
+	}
+}
+
+// contextControllerStarter is a ContextControllerStarter whose controllers exit
+// once their context is cancelled, unless blockExit is set.
+type contextControllerStarter struct {
+	mu        sync.Mutex
+	ctxs      map[string]context.Context
+	dones     map[string]chan struct{}
+	blockExit bool
+}
+
+func newContextControllerStarter() *contextControllerStarter {
+	return &contextControllerStarter{
+		ctxs:  make(map[string]context.Context),
+		dones: make(map[string]chan struct{}),
+	}
+}
+
+func (c *contextControllerStarter) StartControllerWithContext(ctx context.Context, pc *unstructured.Unstructured) (<-chan struct{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	done := make(chan struct{})
+	c.ctxs[pc.GetName()] = ctx
+	c.dones[pc.GetName()] = done
+	if !c.blockExit {
+		go func() {
+			<-ctx.Done()
+			close(done)
+		}()
+	}
+	return done, nil
+}
+
+func (c *contextControllerStarter) get(name string) (context.Context, chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ctxs[name], c.dones[name]
+}
+
+// TestManagerContextControllerStarter verifies that a ContextControllerStarter
+// receives the tenant context and that the context is cancelled on stop.
+func TestManagerContextControllerStarter(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newContextControllerStarter()
+
+	finalizerName := "test-finalizer"
+	manager := newManager(
+		dynamicClient,
+		finalizerName,
+		AdaptContextControllerStarter(starter),
+	)
+
+	pc := createTestProviderConfig("pc-context")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	syncCtx, cancelSync := context.WithCancel(mtcontext.ContextWithTenantUID(ctx, "pc-context"))
+	if err := manager.StartControllersForProviderConfig(syncCtx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	// The controllers must outlive the sync that started them.
+	cancelSync()
+
+	starterCtx, done := starter.get("pc-context")
+	if starterCtx == nil {
+		t.Fatal("Expected StartControllerWithContext to be called")
+	}
+	if got, want := mtcontext.TenantUIDFromContext(starterCtx), "tenant-uid:pc-context"; got != want {
+		t.Errorf("TenantUIDFromContext() = %v, want %v", got, want)
+	}
+	if err := starterCtx.Err(); err != nil {
+		t.Fatalf("Expected starter context to be active after the sync returned, got %v", err)
+	}
+
+	latestPC, err := providerConfigFromClient(ctx, dynamicClient, "pc-context")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	latestPC.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	if err := manager.StopControllersForProviderConfig(ctx, latestPC); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if starterCtx.Err() == nil {
+		t.Error("Expected starter context to be cancelled on stop")
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the controllers to exit once their context is cancelled")
+	}
+	if err := manager.StopControllersForProviderConfig(ctx, latestPC); err != nil {
+		t.Fatalf("Stop after the controllers exited failed: %v", err)
+	}
+	finalPC, err := providerConfigFromClient(ctx, dynamicClient, "pc-context")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if hasFinalizer(finalPC, finalizerName) {
+		t.Error("Expected the finalizer to be removed once the controllers exited")
+	}
+}
+
+// TestManagerContextControllerStarterCrash verifies that controllers started
+// through a ContextControllerStarter are restarted after exiting unexpectedly,
+// and that the context of the crashed controllers is cancelled.
+func TestManagerContextControllerStarterCrash(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newContextControllerStarter()
+	starter.blockExit = true
+
+	manager := newManager(
+		dynamicClient,
+		"test-finalizer",
+		AdaptContextControllerStarter(starter),
+		WithRestartBackoff(0, time.Minute),
+	)
+
+	pc := createTestProviderConfig("pc-context-crash")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	crashedCtx, done := starter.get("pc-context-crash")
+	close(done)
+
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if crashedCtx.Err() == nil {
+		t.Error("Expected context of the crashed controllers to be cancelled")
+	}
+	if restartedCtx, _ := starter.get("pc-context-crash"); restartedCtx == crashedCtx || restartedCtx.Err() != nil {
+		t.Error("Expected controllers to be restarted with a new active context")
+	}
+}
+
+// stopChannelStarter is a ControllerStarter that returns the same stop channel on every start.
+type stopChannelStarter struct {
+	stopCh chan struct{}
+}
+
+func (s *stopChannelStarter) StartController(*unstructured.Unstructured) (chan<- struct{}, error) {
+	return s.stopCh, nil
+}
+
+// TestAdaptControllerStarter verifies that the adapter closes the stop channel
+// of a channel-based starter when the context is cancelled.
+func TestAdaptControllerStarter(t *testing.T) {
+	t.Run("channel starter", func(t *testing.T) {
+		starter := &stopChannelStarter{stopCh: make(chan struct{})}
+		ctx, cancel := context.WithCancel(context.Background())
+		done, err := AdaptControllerStarter(starter).StartControllerWithContext(ctx, createTestProviderConfig("pc-adapt"))
+		if err != nil {
+			t.Fatalf("StartControllerWithContext failed: %v", err)
+		}
+		if done != nil {
+			t.Error("Expected nil done channel for a starter without a handle")
+		}
+		stopCh := starter.stopCh
+		cancel()
+		select {
+		case <-stopCh:
+		case <-time.After(time.Second):
+			t.Fatal("Expected stop channel to be closed after the context was cancelled")
+		}
+	})
+
+	t.Run("handle starter", func(t *testing.T) {
+		starter := newHandleControllerStarter()
+		ctx, cancel := context.WithCancel(context.Background())
+		done, err := AdaptControllerStarter(starter).StartControllerWithContext(ctx, createTestProviderConfig("pc-adapt"))
+		if err != nil {
+			t.Fatalf("StartControllerWithContext failed: %v", err)
+		}
+		stopCh, doneCh := starter.channels("pc-adapt")
+		if done != (<-chan struct{})(doneCh) {
+			t.Error("Expected the Done channel of the handle to be passed through")
+		}
+		cancel()
+		select {
+		case <-stopCh:
+		case <-time.After(time.Second):
+			t.Fatal("Expected stop channel to be closed after the context was cancelled")
+		}
+	})
+
+	t.Run("handle starter readiness", func(t *testing.T) {
+		starter := &readyChannelStarter{mockControllerStarter: newMockControllerStarter(), ready: make(chan struct{})}
+		adapter := AdaptControllerStarter(starter)
+		pc := createTestProviderConfig("pc-adapt")
+		if _, err := adapter.StartControllerWithContext(context.Background(), pc); err != nil {
+			t.Fatalf("StartControllerWithContext failed: %v", err)
+		}
+		rr, ok := readinessReporterOf(adapter)
+		if !ok {
+			t.Fatal("Expected the adapter to report readiness")
+		}
+		if rr.HasSynced(pc) {
+			t.Error("Did not expect the controllers to be ready before Ready is closed")
+		}
+		close(starter.ready)
+		if !rr.HasSynced(pc) {
+			t.Error("Expected the controllers to be ready once Ready is closed")
+		}
+	})
+
+	t.Run("start failure", func(t *testing.T) {
+		starter := newMockControllerStarter()
+		starter.shouldFailStart = true
+		if _, err := AdaptControllerStarter(starter).StartControllerWithContext(context.Background(), createTestProviderConfig("pc-adapt")); err == nil {
+			t.Error("Expected start failure to be returned")
+		}
+	})
+}
+
+// cleanupRecorder records the keys passed to a CleanupHook and can inject failures.
+type cleanupRecorder struct {
+	mu   sync.Mutex
+	keys []string
+	err  error
+}
+
+func (c *cleanupRecorder) hook(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = append(c.keys, key)
+	return c.err
+}
+
+func (c *cleanupRecorder) setErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+func (c *cleanupRecorder) calls() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.keys)
+}
+
+// TestManagerCleanupControllersForDeletedProviderConfig verifies that the controllers
+// of a ProviderConfig deleted without a deletion timestamp are stopped without
+// blocking the worker, that the cleanup hook runs once they exited, and that a
+// failed hook is retried.
+func TestManagerCleanupControllersForDeletedProviderConfig(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newContextControllerStarter()
+	starter.blockExit = true
+	cleanup := &cleanupRecorder{err: fmt.Errorf("injected cleanup failure")}
+	recorder := &requeueRecorder{}
+
+	manager := newManager(
+		dynamicClient,
+		"test-finalizer",
+		AdaptContextControllerStarter(starter),
+		WithCleanupHook(cleanup.hook),
+		WithStopTimeout(time.Minute),
+	)
+	manager.requeueAfter = recorder.requeueAfter
+
+	pc := createTestProviderConfig("pc-orphan")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	starterCtx, done := starter.get("pc-orphan")
+
+	// The worker does not wait for the controllers to exit.
+	if err := manager.CleanupControllersForProviderConfig(ctx, "pc-orphan"); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	if starterCtx.Err() == nil {
+		t.Error("Expected controllers to be signaled to stop")
+	}
+	if got := cleanup.calls(); len(got) != 0 {
+		t.Errorf("Did not expect the hook to run before the controllers exited, got %v", got)
+	}
+	if got := recorder.get(); len(got) != 1 || got[0] != stopCheckInterval {
+		t.Errorf("Expected the cleanup to be checked again after %v, got %v", stopCheckInterval, got)
+	}
+
+	close(done)
+	if err := manager.CleanupControllersForProviderConfig(ctx, "pc-orphan"); err == nil {
+		t.Fatal("Expected cleanup to fail when the hook fails")
+	}
+	if _, exists := manager.controllers.Get("pc-orphan"); !exists {
+		t.Error("Expected controller map entry to be kept until the hook succeeds")
+	}
+
+	cleanup.setErr(nil)
+	if err := manager.CleanupControllersForProviderConfig(ctx, "pc-orphan"); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	if _, exists := manager.controllers.Get("pc-orphan"); exists {
+		t.Error("Expected controller map entry to be removed after cleanup")
+	}
+	if got := cleanup.calls(); !slices.Equal(got, []string{"pc-orphan", "pc-orphan"}) {
+		t.Errorf("Expected the hook to run twice for pc-orphan, got %v", got)
+	}
+
+	// Cleaning up an unknown ProviderConfig does nothing.
+	if err := manager.CleanupControllersForProviderConfig(ctx, "pc-unknown"); err != nil {
+		t.Errorf("Cleanup of unknown ProviderConfig failed: %v", err)
+	}
+	if got := len(cleanup.calls()); got != 2 {
+		t.Errorf("Did not expect the hook to run for an unknown ProviderConfig, got %d calls", got)
+	}
+}
+
+// TestManagerStopKeepsFinalizerUntilCleanupSucceeds verifies that the finalizer of a
+// terminating ProviderConfig is only removed once the cleanup hook succeeds.
+func TestManagerStopKeepsFinalizerUntilCleanupSucceeds(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	cleanup := &cleanupRecorder{err: fmt.Errorf("injected cleanup failure")}
+
+	finalizerName := "test-finalizer"
+	manager := newManager(
+		dynamicClient,
+		finalizerName,
+		newMockControllerStarter(),
+		WithCleanupHook(cleanup.hook),
+	)
+
+	pc := createTestProviderConfig("pc-cleanup")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	latestPC, err := providerConfigFromClient(ctx, dynamicClient, "pc-cleanup")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	latestPC.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	if err := manager.StopControllersForProviderConfig(ctx, latestPC); err == nil {
+		t.Fatal("Expected stop to fail when the cleanup hook fails")
+	}
+	latestPC, err = providerConfigFromClient(ctx, dynamicClient, "pc-cleanup")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if !hasFinalizer(latestPC, finalizerName) {
+		t.Error("Expected finalizer to be kept while the cleanup hook fails")
+	}
+
+	cleanup.setErr(nil)
+	if err := manager.StopControllersForProviderConfig(ctx, latestPC); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	latestPC, err = providerConfigFromClient(ctx, dynamicClient, "pc-cleanup")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if hasFinalizer(latestPC, finalizerName) {
+		t.Error("Expected finalizer to be removed once the cleanup hook succeeded")
+	}
+}
+
+// readyChannelStarter is a HandleControllerStarter whose controllers become
+// ready once the test closes ready.
+type readyChannelStarter struct {
+	*mockControllerStarter
+	ready chan struct{}
+}
+
+func (s *readyChannelStarter) StartControllerWithHandle(pc *unstructured.Unstructured) (*ControllerHandle, error) {
+	stopCh, err := s.StartController(pc)
+	if err != nil {
+		return nil, err
+	}
+	return &ControllerHandle{StopCh: stopCh, Ready: s.ready}, nil
+}
+
+// syncedStarter is a ReadinessReporter whose controllers are ready once the
+// test sets synced.
+type syncedStarter struct {
+	*mockControllerStarter
+	synced atomic.Bool
+}
+
+func (s *syncedStarter) HasSynced(*unstructured.Unstructured) bool {
+	return s.synced.Load()
+}
+
+// TestManagerReadinessChannel verifies that controllers reporting readiness
+// through ControllerHandle.Ready are reported as starting until they are ready.
+func TestManagerReadinessChannel(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := &readyChannelStarter{mockControllerStarter: newMockControllerStarter(), ready: make(chan struct{})}
+	recorder := &requeueRecorder{}
+	manager := newManager(dynamicClient, "test-finalizer", starter)
+	manager.requeueAfter = recorder.requeueAfter
+
+	pc := createTestProviderConfig("pc-ready")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if state := manager.DebugTenants()["pc-ready"].State; state != TenantStateStarting {
+		t.Errorf("Tenant state = %s before the controllers are ready, want %s", state, TenantStateStarting)
+	}
+	ready := conditionFromClient(ctx, t, dynamicClient, "pc-ready", ConditionControllersReady)
+	if ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != ReasonControllersStarting {
+		t.Errorf("Expected %s=False with reason %s, got %+v", ConditionControllersReady, ReasonControllersStarting, ready)
+	}
+
+	close(starter.ready)
+	if err := wait.PollUntilContextTimeout(ctx, 5*time.Millisecond, time.Second, true, func(context.Context) (bool, error) {
+		return slices.Contains(recorder.get(), 0), nil
+	}); err != nil {
+		t.Fatalf("Expected readiness to requeue the ProviderConfig: %v", err)
+	}
+	if state := manager.DebugTenants()["pc-ready"].State; state != TenantStateRunning {
+		t.Errorf("Tenant state = %s once the controllers are ready, want %s", state, TenantStateRunning)
+	}
+
+	// The requeued sync reports the controllers ready without restarting them.
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if got := starter.getStartCallCount(); got != 1 {
+		t.Errorf("Expected 1 start call, got %d", got)
+	}
+	ready = conditionFromClient(ctx, t, dynamicClient, "pc-ready", ConditionControllersReady)
+	if ready == nil || ready.Status != metav1.ConditionTrue || ready.Reason != ReasonControllersReady {
+		t.Errorf("Expected %s=True with reason %s, got %+v", ConditionControllersReady, ReasonControllersReady, ready)
+	}
+}
+
+// TestManagerReadinessTimeout verifies that controllers that do not report
+// ready within the readiness timeout are restarted with backoff.
+func TestManagerReadinessTimeout(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := &syncedStarter{mockControllerStarter: newMockControllerStarter()}
+	recorder := &requeueRecorder{}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	manager := newManager(dynamicClient, "test-finalizer", AdaptContextControllerStarter(AdaptControllerStarter(starter)),
+		WithClock(fakeClock),
+		WithReadinessTimeout(time.Minute),
+		WithRestartBackoff(time.Second, time.Hour),
+	)
+	manager.requeueAfter = recorder.requeueAfter
+
+	pc := createTestProviderConfig("pc-never-ready")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if delays := recorder.get(); !slices.Contains(delays, time.Minute) {
+		t.Errorf("Expected a requeue after the readiness timeout, got %v", delays)
+	}
+
+	fakeClock.Step(time.Minute)
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Sync after the readiness timeout failed: %v", err)
+	}
+	if state := manager.DebugTenants()["pc-never-ready"].State; state != TenantStateStopped {
+		t.Errorf("Tenant state = %s after the readiness timeout, want %s", state, TenantStateStopped)
+	}
+	ready := conditionFromClient(ctx, t, dynamicClient, "pc-never-ready", ConditionControllersReady)
+	if ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != ReasonReadinessTimedOut {
+		t.Errorf("Expected %s=False with reason %s, got %+v", ConditionControllersReady, ReasonReadinessTimedOut, ready)
+	}
+
+	// The controllers are restarted after the backoff and become ready.
+	starter.synced.Store(true)
+	fakeClock.Step(time.Second)
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if got := starter.getStartCallCount(); got != 2 {
+		t.Fatalf("Expected 2 start calls, got %d", got)
+	}
+	if err := wait.PollUntilContextTimeout(ctx, 5*time.Millisecond, time.Second, true, func(context.Context) (bool, error) {
+		return manager.DebugTenants()["pc-never-ready"].State == TenantStateRunning, nil
+	}); err != nil {
+		t.Errorf("Expected the restarted controllers to become ready: %v", err)
+	}
+	if got := manager.DebugTenants()["pc-never-ready"].Starters[0].Restarts; got != 1 {
+		t.Errorf("Expected 1 restart, got %d", got)
+	}
+}
+
+// TestManagerInspectionDuringStarts verifies that the tenants can be inspected,
+// as by the drift reconciler and the debug handler, while the manager starts
+// their controllers and handles their crashes. It is meant to run with the
+// race detector.
+func TestManagerInspectionDuringStarts(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	// Slow status updates widen the window in which the manager changes the
+	// state of the controllers it handles.
+	dynamicClient.PrependReactor("update", testProviderConfigGVR.Resource, func(k8stesting.Action) (bool, runtime.Object, error) {
+		time.Sleep(time.Millisecond)
+		return false, nil, nil
+	})
+	starter := newContextControllerStarter()
+	starter.blockExit = true
+	manager := newManager(dynamicClient, "test-finalizer", AdaptContextControllerStarter(starter),
+		WithRestartBackoff(time.Hour, time.Hour),
+	)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, inspect := range []func(){
+		func() { manager.TrackedProviderConfigs() },
+		func() { manager.DebugTenants() },
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					inspect()
+				}
+			}
+		}()
+	}
+
+	for i := range 50 {
+		name := fmt.Sprintf("pc-%d", i)
+		pc := createTestProviderConfig(name)
+		if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+			t.Fatalf("Failed to create ProviderConfig: %v", err)
+		}
+		if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		if i%2 == 0 {
+			_, done := starter.get(name)
+			close(done)
+			if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+				t.Fatalf("Sync after the crash failed: %v", err)
+			}
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	running := 0
+	for _, isRunning := range manager.TrackedProviderConfigs() {
+		if isRunning {
+			running++
+		}
+	}
+	if running != 25 {
+		t.Errorf("Expected 25 tenants with running controllers, got %d", running)
+	}
+}
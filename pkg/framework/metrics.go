@@ -0,0 +1,205 @@
+package framework
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/mtmetrics"
+)
+
+// metricsSubsystem prefixes the names of all framework metrics.
+const metricsSubsystem = "providerconfig_framework"
+
+// Kinds of drift corrected by the drift reconciler, used as the "kind" label.
+const (
+	// driftMissingTenant is a live ProviderConfig without controllers.
+	driftMissingTenant = "missing_tenant"
+	// driftOrphanedTenant is a tenant with controllers but no ProviderConfig.
+	driftOrphanedTenant = "orphaned_tenant"
+	// driftMissingFinalizer is a ProviderConfig with running controllers but
+	// without the framework finalizer.
+	driftMissingFinalizer = "missing_finalizer"
+)
+
+// Reasons of stop failures that are not condition reasons, used as the
+// "reason" label of the stop failures metric.
+const (
+	// stopReasonCleanupFailed means the cleanup hook failed.
+	stopReasonCleanupFailed = "CleanupHookFailed"
+	// stopReasonShutdownTimedOut means the controllers did not exit in time
+	// while all tenants were stopped, e.g. on shutdown.
+	stopReasonShutdownTimedOut = "ShutdownTimedOut"
+)
+
+// Finalizer operations, used as the "operation" label of the finalizer errors metric.
+const (
+	finalizerOperationAdd    = "add"
+	finalizerOperationRemove = "remove"
+)
+
+// metrics holds the metrics emitted by the Controller.
+type metrics struct {
+	// driftCorrections counts the corrections made by the drift reconciler by kind.
+	driftCorrections mtmetrics.CounterVec
+	// pausedTenants is the number of tenants paused through PausedAnnotation.
+	pausedTenants prometheus.Gauge
+	// tenants is the number of tenants known to the controller by TenantState.
+	tenants mtmetrics.GaugeVec
+}
+
+// managerMetrics holds the metrics emitted by the manager.
+type managerMetrics struct {
+	// startDuration is how long successful starts took, from admission until
+	// the starters returned.
+	startDuration prometheus.Histogram
+	// stopDuration is how long successful stops took, including the finalizer removal.
+	stopDuration prometheus.Histogram
+	// startFailures and stopFailures count failed starts and stops by reason.
+	startFailures mtmetrics.CounterVec
+	stopFailures  mtmetrics.CounterVec
+	// finalizerErrors counts failed finalizer operations by operation.
+	finalizerErrors mtmetrics.CounterVec
+	// timeToRunning is the time from the creation of a ProviderConfig until
+	// its controllers first ran.
+	timeToRunning prometheus.Histogram
+	// panics counts the panics recovered in tenant controller goroutines by starter.
+	panics mtmetrics.CounterVec
+}
+
+// newMetrics registers the framework metrics through factory. Without a
+// factory, the metrics are kept in a private registry and not exported.
+// Metrics that fail to register are logged and not exported either, so that
+// metrics never prevent the framework from running.
+func newMetrics(factory mtmetrics.MetricFactory) *metrics {
+	if factory == nil {
+		factory = mtmetrics.NewStdMetricFactory(prometheus.NewRegistry())
+	}
+	return &metrics{
+		driftCorrections: newCounterVec(factory, prometheus.CounterOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "drift_corrections_total",
+			Help:      "Number of differences between running tenant controllers and ProviderConfigs corrected by the drift reconciler, by kind.",
+		}, []string{"kind"}),
+		pausedTenants: newGauge(factory, prometheus.GaugeOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "paused_tenants",
+			Help:      "Number of tenants whose controllers are paused through the " + PausedAnnotation + " annotation.",
+		}),
+		tenants: newGaugeVec(factory, prometheus.GaugeOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "managed_tenants",
+			Help:      "Number of tenants known to the framework, by state.",
+		}, []string{"state"}),
+	}
+}
+
+// newManagerMetrics registers the manager metrics through factory, like newMetrics.
+func newManagerMetrics(factory mtmetrics.MetricFactory) *managerMetrics {
+	if factory == nil {
+		factory = mtmetrics.NewStdMetricFactory(prometheus.NewRegistry())
+	}
+	return &managerMetrics{
+		startDuration: newHistogram(factory, prometheus.HistogramOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "tenant_start_duration_seconds",
+			Help:      "Time taken to start the controllers of a tenant, including adding the finalizer.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 15),
+		}),
+		stopDuration: newHistogram(factory, prometheus.HistogramOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "tenant_stop_duration_seconds",
+			Help:      "Time taken to stop the controllers of a deleted tenant, including removing the finalizer.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 15),
+		}),
+		startFailures: newCounterVec(factory, prometheus.CounterOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "tenant_start_failures_total",
+			Help:      "Number of failed starts of tenant controllers, by reason.",
+		}, []string{"reason"}),
+		stopFailures: newCounterVec(factory, prometheus.CounterOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "tenant_stop_failures_total",
+			Help:      "Number of failed or timed out stops of tenant controllers, by reason.",
+		}, []string{"reason"}),
+		finalizerErrors: newCounterVec(factory, prometheus.CounterOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "finalizer_errors_total",
+			Help:      "Number of failed finalizer updates on ProviderConfigs, by operation.",
+		}, []string{"operation"}),
+		timeToRunning: newHistogram(factory, prometheus.HistogramOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "tenant_time_to_running_seconds",
+			Help:      "Time from the creation of a ProviderConfig until its controllers first ran.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+		}),
+		panics: newCounterVec(factory, prometheus.CounterOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "tenant_panics_total",
+			Help:      "Number of panics recovered in tenant controller goroutines started through Go, by starter.",
+		}, []string{"starter"}),
+	}
+}
+
+// newCounterVec registers a CounterVec through factory, falling back to an
+// unregistered one on failure.
+func newCounterVec(factory mtmetrics.MetricFactory, opts prometheus.CounterOpts, labelNames []string) mtmetrics.CounterVec {
+	vec, err := factory.NewCounterVec(opts, labelNames)
+	if err != nil {
+		klog.ErrorS(err, "Failed to register framework metric; it will not be exported", "metric", prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name))
+		return prometheus.NewCounterVec(opts, labelNames)
+	}
+	return vec
+}
+
+// newGauge registers a Gauge through factory, falling back to an unregistered
+// one on failure.
+func newGauge(factory mtmetrics.MetricFactory, opts prometheus.GaugeOpts) prometheus.Gauge {
+	gauge, err := factory.NewGauge(opts)
+	if err != nil {
+		klog.ErrorS(err, "Failed to register framework metric; it will not be exported", "metric", prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name))
+		return prometheus.NewGauge(opts)
+	}
+	return gauge
+}
+
+// newGaugeVec registers a GaugeVec through factory, falling back to an
+// unregistered one on failure.
+func newGaugeVec(factory mtmetrics.MetricFactory, opts prometheus.GaugeOpts, labelNames []string) mtmetrics.GaugeVec {
+	vec, err := factory.NewGaugeVec(opts, labelNames)
+	if err != nil {
+		klog.ErrorS(err, "Failed to register framework metric; it will not be exported", "metric", prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name))
+		return prometheus.NewGaugeVec(opts, labelNames)
+	}
+	return vec
+}
+
+// newHistogram registers a Histogram through factory, falling back to an
+// unregistered one on failure.
+func newHistogram(factory mtmetrics.MetricFactory, opts prometheus.HistogramOpts) prometheus.Histogram {
+	histogram, err := factory.NewHistogram(opts)
+	if err != nil {
+		klog.ErrorS(err, "Failed to register framework metric; it will not be exported", "metric", prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name))
+		return prometheus.NewHistogram(opts)
+	}
+	return histogram
+}
+
+// updateTenantMetrics sets the managed tenants metric from the tenants known
+// to the controller. It walks every tenant, so it runs on the ticks of the
+// drift reconciler rather than after every sync.
+func (c *Controller) updateTenantMetrics() {
+	counts := map[TenantState]int{
+		TenantStateRunning:    0,
+		TenantStateStarting:   0,
+		TenantStateDegraded:   0,
+		TenantStateStopped:    0,
+		TenantStatePaused:     0,
+		TenantStateNotStarted: 0,
+	}
+	for _, info := range c.collectTenants() {
+		counts[info.State]++
+	}
+	for state, n := range counts {
+		c.metrics.tenants.WithLabelValues(string(state)).Set(float64(n))
+	}
+}
@@ -0,0 +1,239 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/mtmetrics"
+)
+
+// gatherMetrics returns the metrics in reg with the given name, keyed by the
+// value of label, or by "" for metrics without it.
+func gatherMetrics(t *testing.T, reg prometheus.Gatherer, name, label string) map[string]*dto.Metric {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	metrics := map[string]*dto.Metric{}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			value := ""
+			for _, pair := range m.GetLabel() {
+				if pair.GetName() == label {
+					value = pair.GetValue()
+				}
+			}
+			metrics[value] = m
+		}
+	}
+	return metrics
+}
+
+// TestManagerLifecycleMetrics verifies the start, stop and time to running
+// metrics of a tenant.
+func TestManagerLifecycleMetrics(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := testingclock.NewFakeClock(created.Add(90 * time.Second))
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newMockControllerStarter()
+	manager := newManager(dynamicClient, "test-finalizer", starter,
+		WithMetricFactory(mtmetrics.NewStdMetricFactory(reg)),
+		WithClock(fakeClock),
+	)
+
+	pc := createTestProviderConfig("pc-metrics")
+	pc.SetCreationTimestamp(metav1.NewTime(created))
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if m := gatherMetrics(t, reg, "providerconfig_framework_tenant_start_duration_seconds", "")[""]; m.GetHistogram().GetSampleCount() != 1 {
+		t.Errorf("Expected 1 start duration sample, got %v", m)
+	}
+	timeToRunning := gatherMetrics(t, reg, "providerconfig_framework_tenant_time_to_running_seconds", "")[""]
+	if got := timeToRunning.GetHistogram().GetSampleCount(); got != 1 {
+		t.Fatalf("Expected 1 time to running sample, got %d", got)
+	}
+	if got := timeToRunning.GetHistogram().GetSampleSum(); got != 90 {
+		t.Errorf("Time to running = %vs, want 90s", got)
+	}
+
+	latest, err := providerConfigFromClient(ctx, dynamicClient, "pc-metrics")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	// Restarting the controllers of a tenant that already has the finalizer is
+	// not its first start.
+	manager.controllers.Delete("pc-metrics")
+	if err := manager.StartControllersForProviderConfig(ctx, latest); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if got := gatherMetrics(t, reg, "providerconfig_framework_tenant_time_to_running_seconds", "")[""].GetHistogram().GetSampleCount(); got != 1 {
+		t.Errorf("Expected time to running to be observed once, got %d samples", got)
+	}
+
+	latest.SetDeletionTimestamp(&metav1.Time{Time: fakeClock.Now()})
+	if err := manager.StopControllersForProviderConfig(ctx, latest); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if m := gatherMetrics(t, reg, "providerconfig_framework_tenant_stop_duration_seconds", "")[""]; m.GetHistogram().GetSampleCount() != 1 {
+		t.Errorf("Expected 1 stop duration sample, got %v", m)
+	}
+}
+
+// TestManagerFailureMetrics verifies that failed starts, stops and finalizer
+// updates are counted by reason.
+func TestManagerFailureMetrics(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newMockControllerStarter()
+	starter.shouldFailStart = true
+	cleanup := &cleanupRecorder{err: errors.New("injected cleanup failure")}
+	manager := newManager(dynamicClient, "test-finalizer", starter,
+		WithMetricFactory(mtmetrics.NewStdMetricFactory(reg)),
+		WithCleanupHook(cleanup.hook),
+	)
+
+	failing := createTestProviderConfig("pc-failing")
+	if err := createProviderConfigInClient(ctx, dynamicClient, failing); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, failing); err == nil {
+		t.Fatal("Expected start to fail")
+	}
+
+	conflicting := createTestProviderConfig("pc-conflicting")
+	if err := createProviderConfigInClient(ctx, dynamicClient, conflicting); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	injectPatchConflicts(dynamicClient, -1)
+	if err := manager.StartControllersForProviderConfig(ctx, conflicting); err == nil {
+		t.Fatal("Expected start to fail")
+	}
+
+	deleted := createTestProviderConfig("pc-deleted")
+	deleted.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	if err := manager.StopControllersForProviderConfig(ctx, deleted); err == nil {
+		t.Fatal("Expected stop to fail")
+	}
+
+	startFailures := gatherMetrics(t, reg, "providerconfig_framework_tenant_start_failures_total", "reason")
+	for _, reason := range []string{ReasonControllerStartFailed, ReasonFinalizerUpdateFailed} {
+		if got := startFailures[reason].GetCounter().GetValue(); got != 1 {
+			t.Errorf("Start failures with reason %s = %v, want 1", reason, got)
+		}
+	}
+	stopFailures := gatherMetrics(t, reg, "providerconfig_framework_tenant_stop_failures_total", "reason")
+	if got := stopFailures[stopReasonCleanupFailed].GetCounter().GetValue(); got != 1 {
+		t.Errorf("Stop failures with reason %s = %v, want 1", stopReasonCleanupFailed, got)
+	}
+	finalizerErrors := gatherMetrics(t, reg, "providerconfig_framework_finalizer_errors_total", "operation")
+	if got := finalizerErrors[finalizerOperationAdd].GetCounter().GetValue(); got != 1 {
+		t.Errorf("Finalizer add errors = %v, want 1", got)
+	}
+}
+
+// TestManagedTenantsMetric verifies that the managed tenants metric counts
+// the tenants known to the controller by state.
+func TestManagedTenantsMetric(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	starter := &selectiveFailingStarter{fail: map[string]bool{"pc-failing": true}}
+	ctrl := New(dynamicClient, &fakeInformer{Indexer: indexer, synced: true}, "test-finalizer", starter, make(chan struct{}),
+		WithMetricFactory(mtmetrics.NewStdMetricFactory(reg)),
+	)
+
+	for _, name := range []string{"pc-running-1", "pc-running-2", "pc-failing"} {
+		pc := createTestProviderConfig(name)
+		if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+			t.Fatalf("Failed to create ProviderConfig: %v", err)
+		}
+		if err := indexer.Add(pc); err != nil {
+			t.Fatalf("Failed to add ProviderConfig to indexer: %v", err)
+		}
+		_ = ctrl.syncWrapper(ctx, name)
+	}
+	ctrl.updateTenantMetrics()
+
+	tenants := gatherMetrics(t, reg, "providerconfig_framework_managed_tenants", "state")
+	want := map[TenantState]float64{
+		TenantStateRunning:    2,
+		TenantStateNotStarted: 1,
+		TenantStatePaused:     0,
+		TenantStateStopped:    0,
+	}
+	for state, count := range want {
+		if got := tenants[string(state)].GetGauge().GetValue(); got != count {
+			t.Errorf("Managed tenants in state %s = %v, want %v", state, got, count)
+		}
+	}
+}
+
+// TestManagedTenantsMetricUpdatedOnTick verifies that the managed tenants
+// metric is updated periodically rather than after every sync, also when
+// drift reconciliation is disabled.
+func TestManagedTenantsMetricUpdatedOnTick(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	ctrl := New(dynamicClient, &fakeInformer{Indexer: indexer, synced: true}, "test-finalizer", newMockControllerStarter(), make(chan struct{}),
+		WithMetricFactory(mtmetrics.NewStdMetricFactory(reg)),
+		WithClock(fakeClock),
+		WithDriftReconcileInterval(0),
+	)
+
+	pc := createTestProviderConfig("pc-running")
+	if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := indexer.Add(pc); err != nil {
+		t.Fatalf("Failed to add ProviderConfig to indexer: %v", err)
+	}
+	if err := ctrl.syncWrapper(ctx, "pc-running"); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	running := func() float64 {
+		return gatherMetrics(t, reg, "providerconfig_framework_managed_tenants", "state")[string(TenantStateRunning)].GetGauge().GetValue()
+	}
+	if got := running(); got != 0 {
+		t.Errorf("Running tenants = %v after a sync, want the metric to wait for the next tick", got)
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go ctrl.runDriftReconciler(stopCh)
+	if err := wait.PollUntilContextTimeout(ctx, 5*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		if !fakeClock.HasWaiters() {
+			return false, nil
+		}
+		fakeClock.Step(tenantMetricsInterval)
+		return running() == 1, nil
+	}); err != nil {
+		t.Errorf("Expected the metric to count the running tenant after a tick: %v", err)
+	}
+}
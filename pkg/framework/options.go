@@ -0,0 +1,399 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/clock"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework/taskqueue"
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/mtmetrics"
+)
+
+const (
+	// defaultStopTimeout is how long the framework waits by default for the
+	// controllers of a ProviderConfig to acknowledge a stop.
+	defaultStopTimeout = 30 * time.Second
+	// defaultInitialRestartBackoff and defaultMaxRestartBackoff bound the delay
+	// before restarting controllers that exited unexpectedly.
+	defaultInitialRestartBackoff = time.Second
+	defaultMaxRestartBackoff     = 5 * time.Minute
+	// defaultDriftReconcileInterval is how often the drift reconciler runs by default.
+	defaultDriftReconcileInterval = 5 * time.Minute
+	// defaultShutdownTimeout is how long the framework waits by default for the
+	// controllers of all tenants to exit when it shuts down.
+	defaultShutdownTimeout = 30 * time.Second
+	// defaultReadinessTimeout is how long controllers have by default to become
+	// ready before they are restarted.
+	defaultReadinessTimeout = 10 * time.Minute
+)
+
+// SpecChangePolicy selects how the framework reacts when the spec of a
+// ProviderConfig with running controllers changes.
+type SpecChangePolicy int
+
+const (
+	// SpecChangePolicyRestart stops the running controllers and starts them
+	// again with the new spec.
+	SpecChangePolicyRestart SpecChangePolicy = iota
+	// SpecChangePolicyUpdate passes the new spec to the starter through
+	// ControllerUpdater. Starters that do not implement ControllerUpdater
+	// are restarted instead.
+	SpecChangePolicyUpdate
+	// SpecChangePolicyIgnore leaves the running controllers untouched. Spec
+	// changes take effect the next time the controllers are started.
+	SpecChangePolicyIgnore
+)
+
+// String returns the name of the policy.
+func (p SpecChangePolicy) String() string {
+	switch p {
+	case SpecChangePolicyRestart:
+		return "Restart"
+	case SpecChangePolicyUpdate:
+		return "Update"
+	case SpecChangePolicyIgnore:
+		return "Ignore"
+	default:
+		return fmt.Sprintf("SpecChangePolicy(%d)", int(p))
+	}
+}
+
+// Option configures a Controller created by New.
+type Option func(*options)
+
+// options holds the tunables of the framework. The zero value is not valid;
+// use newOptions to obtain the defaults. Invalid values passed to an Option are
+// recorded in errs and leave the default in place; a Controller with invalid
+// options refuses to run.
+type options struct {
+	// workers is the number of workers syncing ProviderConfigs.
+	workers int
+	// queueName names the ProviderConfig queue, e.g. for workqueue metrics.
+	queueName string
+	// queueOptions tune the ProviderConfig queue.
+	queueOptions []taskqueue.Option
+	// tenants describes the resource whose objects define tenants.
+	tenants tenantResource
+	// clock is used for restart backoff and stop timeouts, and by the queue.
+	clock clock.WithTicker
+
+	// stopTimeout bounds how long the manager waits for controllers to exit
+	// before removing the finalizer of a terminating ProviderConfig.
+	stopTimeout time.Duration
+	// shutdownTimeout bounds how long the controller waits for the controllers
+	// of all tenants to exit when it shuts down.
+	shutdownTimeout time.Duration
+	// specChangePolicy selects how spec changes reach running controllers.
+	specChangePolicy SpecChangePolicy
+	// initialRestartBackoff and maxRestartBackoff bound the exponential delay
+	// before controllers that exited unexpectedly are restarted.
+	initialRestartBackoff time.Duration
+	maxRestartBackoff     time.Duration
+	// readinessTimeout bounds how long started controllers may take to become
+	// ready before they are restarted; zero waits forever.
+	readinessTimeout time.Duration
+	// maxConcurrentStarts, startQPS, startBurst and startJitter tune the
+	// admission control of tenant starts. Zero values disable the limits.
+	maxConcurrentStarts int
+	startQPS            float64
+	startBurst          int
+	startJitter         time.Duration
+	// namedStarters are the starters registered in addition to the one passed to New.
+	namedStarters []namedStarter
+	// leaderElection enables leader election when non-nil.
+	leaderElection *LeaderElectionConfig
+	// sharding enables sharding across replicas when non-nil.
+	sharding *ShardingConfig
+	// driftReconcileInterval is how often the drift reconciler runs; zero disables it.
+	driftReconcileInterval time.Duration
+	// events enables Events on ProviderConfigs when non-nil.
+	events *EventConfig
+	// metricFactory registers the framework metrics when non-nil.
+	metricFactory mtmetrics.MetricFactory
+	// cleanupHook runs after the controllers of a deleted ProviderConfig stopped.
+	cleanupHook CleanupHook
+
+	errs []error
+}
+
+// newOptions returns the default options with opts applied in order.
+func newOptions(opts ...Option) options {
+	o := options{
+		workers:                workersCount,
+		queueName:              providerConfigControllerName,
+		tenants:                defaultTenantResource(),
+		clock:                  clock.RealClock{},
+		driftReconcileInterval: defaultDriftReconcileInterval,
+		stopTimeout:            defaultStopTimeout,
+		shutdownTimeout:        defaultShutdownTimeout,
+		specChangePolicy:       SpecChangePolicyRestart,
+		initialRestartBackoff:  defaultInitialRestartBackoff,
+		maxRestartBackoff:      defaultMaxRestartBackoff,
+		readinessTimeout:       defaultReadinessTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// validate reports the invalid values passed to the options and the options
+// that cannot be combined.
+func (o options) validate() error {
+	errs := slices.Clone(o.errs)
+	if o.sharding != nil {
+		if o.leaderElection != nil {
+			errs = append(errs, errors.New("sharding and leader election are mutually exclusive"))
+		} else if err := o.sharding.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid sharding configuration: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithWorkers sets how many ProviderConfigs are synced in parallel. The
+// default is 5.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		if n <= 0 {
+			o.errs = append(o.errs, fmt.Errorf("worker count must be positive, got %d", n))
+			return
+		}
+		o.workers = n
+	}
+}
+
+// WithQueueName sets the name of the ProviderConfig queue, which also names
+// its workqueue metrics. The default is "provider-config-controller".
+func WithQueueName(name string) Option {
+	return func(o *options) {
+		o.queueName = name
+	}
+}
+
+// WithQueueOptions tunes the ProviderConfig queue, e.g. its rate limiter and
+// requeue limit. It accepts the same options as
+// taskqueue.NewPeriodicTaskQueueWithMultipleWorkers, except for
+// taskqueue.WithKeyFunc and taskqueue.WithClock: the framework sets the key
+// function and clock of the queue, see WithKeyFunc and WithClock.
+func WithQueueOptions(opts ...taskqueue.Option) Option {
+	return func(o *options) {
+		if err := taskqueue.ValidateOptions(opts...); err != nil {
+			o.errs = append(o.errs, fmt.Errorf("invalid queue options: %w", err))
+			return
+		}
+		if taskqueue.SetsKeyFunc(opts...) {
+			o.errs = append(o.errs, errors.New("queue options must not set the key function; use WithKeyFunc instead"))
+			return
+		}
+		if taskqueue.SetsClock(opts...) {
+			o.errs = append(o.errs, errors.New("queue options must not set the clock; use WithClock instead"))
+			return
+		}
+		o.queueOptions = append(o.queueOptions, opts...)
+	}
+}
+
+// WithClock sets the clock used for restart backoff, stop timeouts and the
+// ProviderConfig queue, e.g. a fake clock in tests.
+func WithClock(c clock.WithTicker) Option {
+	return func(o *options) {
+		if c == nil {
+			o.errs = append(o.errs, errors.New("clock must not be nil"))
+			return
+		}
+		o.clock = c
+	}
+}
+
+// WithDriftReconcileInterval sets how often the drift reconciler compares the
+// running tenant controllers with the ProviderConfigs. It starts missing
+// tenants, stops tenants whose ProviderConfig is gone and re-adds removed
+// finalizers. The default is 5 minutes; zero disables the reconciler.
+func WithDriftReconcileInterval(d time.Duration) Option {
+	return func(o *options) {
+		if d < 0 {
+			o.errs = append(o.errs, fmt.Errorf("drift reconcile interval must not be negative, got %v", d))
+			return
+		}
+		o.driftReconcileInterval = d
+	}
+}
+
+// WithMetricFactory registers the framework metrics through factory. Without
+// it, no metrics are exported.
+func WithMetricFactory(factory mtmetrics.MetricFactory) Option {
+	return func(o *options) {
+		o.metricFactory = factory
+	}
+}
+
+// WithStopTimeout sets how long the framework waits for the controllers of a
+// terminating ProviderConfig to exit before it removes the finalizer anyway.
+// It bounds the wait for the Done channel of a HandleControllerStarter handle
+// and for the done channel returned by a ContextControllerStarter; controllers
+// of other starters are not waited for. A zero timeout removes the finalizer
+// without waiting.
+func WithStopTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d < 0 {
+			o.errs = append(o.errs, fmt.Errorf("stop timeout must not be negative, got %v", d))
+			return
+		}
+		o.stopTimeout = d
+	}
+}
+
+// WithShutdownTimeout sets the deadline for stopping the controllers of all
+// tenants when the Controller shuts down, loses leadership or leaves its shard
+// group. The controllers of every tenant are signaled to stop at once, and the
+// tenants whose controllers did not exit by the deadline are reported. Their
+// finalizers are kept, since the tenant objects still exist. The default is
+// 30 seconds; zero does not wait.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d < 0 {
+			o.errs = append(o.errs, fmt.Errorf("shutdown timeout must not be negative, got %v", d))
+			return
+		}
+		o.shutdownTimeout = d
+	}
+}
+
+// WithReadinessTimeout sets how long controllers that report readiness, through
+// ControllerHandle.Ready or ReadinessReporter, may take to become ready after
+// they are started. Controllers that are not ready in time are restarted with
+// the restart backoff (see WithRestartBackoff). The default is 10 minutes; zero
+// waits forever, but still releases the start slot of WithMaxConcurrentStarts
+// after 10 minutes.
+func WithReadinessTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d < 0 {
+			o.errs = append(o.errs, fmt.Errorf("readiness timeout must not be negative, got %v", d))
+			return
+		}
+		o.readinessTimeout = d
+	}
+}
+
+// WithSpecChangePolicy sets how the framework reacts when the spec of a
+// ProviderConfig with running controllers changes. The default is
+// SpecChangePolicyRestart.
+func WithSpecChangePolicy(p SpecChangePolicy) Option {
+	return func(o *options) {
+		o.specChangePolicy = p
+	}
+}
+
+// WithRestartBackoff sets the delay before controllers that exited unexpectedly
+// are restarted. The delay starts at initial and doubles with every consecutive
+// crash up to max. Controllers that ran for at least max before crashing are
+// restarted after the initial delay again.
+func WithRestartBackoff(initial, max time.Duration) Option {
+	return func(o *options) {
+		if initial < 0 || max < initial {
+			o.errs = append(o.errs, fmt.Errorf("restart backoff requires 0 <= initial <= max, got initial %v and max %v", initial, max))
+			return
+		}
+		o.initialRestartBackoff = initial
+		o.maxRestartBackoff = max
+	}
+}
+
+// WithMaxConcurrentStarts limits how many tenants start their controllers at
+// the same time. A tenant counts as starting until its controllers are ready
+// or the readiness timeout expires, see WithReadinessTimeout; without a
+// readiness timeout, for at most 10 minutes. Tenants over the
+// limit are reported as pending and retried later. Zero, the default, does not
+// limit concurrent starts.
+func WithMaxConcurrentStarts(n int) Option {
+	return func(o *options) {
+		if n < 0 {
+			o.errs = append(o.errs, fmt.Errorf("max concurrent starts must not be negative, got %d", n))
+			return
+		}
+		o.maxConcurrentStarts = n
+	}
+}
+
+// WithStartRateLimit limits tenant starts with a token bucket that refills at
+// qps tokens per second and holds up to burst tokens. Tenants without a token
+// are reported as pending and retried once a token is available. By default,
+// the start rate is not limited.
+func WithStartRateLimit(qps float64, burst int) Option {
+	return func(o *options) {
+		if qps <= 0 || burst < 1 {
+			o.errs = append(o.errs, fmt.Errorf("start rate limit requires qps > 0 and burst >= 1, got qps %v and burst %d", qps, burst))
+			return
+		}
+		o.startQPS = qps
+		o.startBurst = burst
+	}
+}
+
+// WithStartJitter adds a random delay of up to d to the retry of pending
+// tenants, so that tenants held back by WithMaxConcurrentStarts or
+// WithStartRateLimit do not retry in lockstep.
+func WithStartJitter(d time.Duration) Option {
+	return func(o *options) {
+		if d < 0 {
+			o.errs = append(o.errs, fmt.Errorf("start jitter must not be negative, got %v", d))
+			return
+		}
+		o.startJitter = d
+	}
+}
+
+// WithNamedControllerStarter registers a ControllerStarter under the given name
+// in addition to the starter passed to New, which is registered as
+// DefaultControllerStarterName. The controllers of each starter are started,
+// stopped and restarted independently. The starter only runs for
+// ProviderConfigs whose labels match selector; a nil selector matches every
+// ProviderConfig. Starter names must be unique, non-empty and differ from
+// DefaultControllerStarterName; invalid starters are not registered.
+func WithNamedControllerStarter(name string, starter ControllerStarter, selector labels.Selector) Option {
+	return func(o *options) {
+		switch {
+		case name == "":
+			o.errs = append(o.errs, errors.New("named controller starter requires a name"))
+			return
+		case name == DefaultControllerStarterName:
+			o.errs = append(o.errs, fmt.Errorf("controller starter name %q is reserved for the starter passed to New", name))
+			return
+		case starter == nil:
+			o.errs = append(o.errs, fmt.Errorf("controller starter %q must not be nil", name))
+			return
+		case slices.ContainsFunc(o.namedStarters, func(s namedStarter) bool { return s.name == name }):
+			o.errs = append(o.errs, fmt.Errorf("controller starter %q is already registered", name))
+			return
+		}
+		o.namedStarters = append(o.namedStarters, namedStarter{
+			name:     name,
+			starter:  starter,
+			selector: selector,
+		})
+	}
+}
+
+// CleanupHook releases tenant resources outside of the tenant controllers once
+// the controllers of a deleted ProviderConfig have stopped. key identifies the
+// ProviderConfig. The hook may run more than once for the same ProviderConfig
+// and must be idempotent.
+type CleanupHook func(ctx context.Context, key string) error
+
+// WithCleanupHook registers a hook that runs after the controllers of a deleted
+// ProviderConfig have stopped. For ProviderConfigs deleted through their
+// finalizer, the finalizer is only removed once the hook succeeds. For
+// ProviderConfigs that disappear without a deletion timestamp, e.g. because they
+// were force-deleted, the hook runs if this replica was running their
+// controllers. Failures are retried with backoff.
+func WithCleanupHook(hook CleanupHook) Option {
+	return func(o *options) {
+		o.cleanupHook = hook
+	}
+}
@@ -0,0 +1,38 @@
+package framework
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// PausedAnnotation pauses the controllers of a ProviderConfig when set to
+// "true". The controllers are stopped but the finalizer is kept, and they are
+// started again once the annotation is removed or set to another value.
+const PausedAnnotation = "tenancy.gke.io/paused"
+
+// isPaused reports whether pc is annotated to pause its controllers.
+func isPaused(pc *unstructured.Unstructured) bool {
+	return pc.GetAnnotations()[PausedAnnotation] == "true"
+}
+
+// wasPaused reports whether the status of pc says its controllers are paused.
+func wasPaused(pc *unstructured.Unstructured) bool {
+	conditions, err := conditionsFromUnstructured(pc)
+	if err != nil {
+		return false
+	}
+	return meta.IsStatusConditionTrue(conditions, ConditionPaused)
+}
+
+// setPaused records whether the controllers of key are paused and updates the
+// paused tenants metric.
+func (c *Controller) setPaused(key string, paused bool) {
+	c.pausedMu.Lock()
+	defer c.pausedMu.Unlock()
+	if paused {
+		c.paused[key] = true
+	} else {
+		delete(c.paused, key)
+	}
+	c.metrics.pausedTenants.Set(float64(len(c.paused)))
+}
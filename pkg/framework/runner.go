@@ -0,0 +1,86 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// panicHandlerKey is the context key of the panicHandler of a tenant.
+type panicHandlerKey struct{}
+
+// panicHandler is called with a panic recovered by Go and its stack.
+type panicHandler func(value any, stack []byte)
+
+// tenantPanic is a panic recovered in a goroutine of tenant controllers.
+type tenantPanic struct {
+	message string
+	stack   string
+	time    time.Time
+}
+
+// withPanicHandler returns a copy of ctx in which Go reports panics to handler.
+func withPanicHandler(ctx context.Context, handler panicHandler) context.Context {
+	return context.WithValue(ctx, panicHandlerKey{}, handler)
+}
+
+// Go runs fn in a new goroutine on behalf of the tenant controllers started
+// with ctx, the context passed to StartControllerWithContext or derived from
+// it. fn is called with ctx, which is cancelled once the controllers must stop.
+//
+// If fn panics, the panic is recovered instead of crashing the process, so the
+// controllers of other tenants keep running. The panic is logged and recorded
+// with its stack for the tenant, the tenant is marked degraded, and its
+// controllers are restarted with the restart backoff (see WithRestartBackoff).
+// Outside of a starter context, the panic is only recovered and logged.
+//
+// Only goroutines run through Go are isolated. A panic in any other goroutine,
+// including every goroutine of channel- and handle-based starters, which have
+// no starter context, still crashes the process. Starters that need isolation
+// implement ContextControllerStarter and run their goroutines through Go.
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	go func() {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			stack := debug.Stack()
+			klog.FromContext(ctx).Error(fmt.Errorf("panic: %v", r), "Recovered from panic in tenant controller goroutine", "stack", string(stack))
+			if handler, ok := ctx.Value(panicHandlerKey{}).(panicHandler); ok {
+				handler(r, stack)
+			}
+		}()
+		fn(ctx)
+	}()
+}
+
+// panicHandlerFor returns the panicHandler of the controllers about to be
+// started in sc. It records the panic in sc and, unless the controllers were
+// already asked to stop through stopSignal, sets panicked, marks the tenant
+// degraded and requeues the ProviderConfig key so that its sync restarts the
+// controllers. panicked and stopSignal belong to this start, so that a panic
+// in the goroutines of stopped controllers does not restart their successors.
+func (m *manager) panicHandlerFor(pcKey, starterName string, cs *ControllerSet, sc *starterControllers, panicked *atomic.Bool, stopSignal <-chan struct{}) panicHandler {
+	return func(value any, stack []byte) {
+		sc.panics.Add(1)
+		sc.lastPanic.Store(&tenantPanic{message: fmt.Sprint(value), stack: string(stack), time: m.clock.Now()})
+		m.metrics.panics.WithLabelValues(starterName).Inc()
+		select {
+		case <-stopSignal:
+			return
+		default:
+		}
+		if !panicked.CompareAndSwap(false, true) {
+			return
+		}
+		cs.degraded.Store(true)
+		if m.requeueAfter != nil {
+			m.requeueAfter(pcKey, 0)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/klog/v2"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/mtmetrics"
+)
+
+// panickingStarter is a ContextControllerStarter whose controllers run a
+// goroutine through Go that panics on the first start for the ProviderConfigs
+// in panicFor.
+type panickingStarter struct {
+	*contextControllerStarter
+	panicFor map[string]bool
+
+	mu     sync.Mutex
+	starts map[string]int
+}
+
+func (s *panickingStarter) StartControllerWithContext(ctx context.Context, pc *unstructured.Unstructured) (<-chan struct{}, error) {
+	s.mu.Lock()
+	if s.starts == nil {
+		s.starts = map[string]int{}
+	}
+	s.starts[pc.GetName()]++
+	first := s.starts[pc.GetName()] == 1
+	s.mu.Unlock()
+
+	done, err := s.contextControllerStarter.StartControllerWithContext(ctx, pc)
+	if err != nil {
+		return nil, err
+	}
+	if first && s.panicFor[pc.GetName()] {
+		Go(ctx, func(context.Context) {
+			panic("injected panic in " + pc.GetName())
+		})
+	}
+	return done, nil
+}
+
+func (s *panickingStarter) startCount(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.starts[name]
+}
+
+// TestManagerRecoversPanicsInTenantGoroutines verifies that a panic in a
+// goroutine of the controllers of one tenant is recorded, marks the tenant
+// degraded and restarts its controllers after a backoff, while the
+// controllers of other tenants keep running.
+func TestManagerRecoversPanicsInTenantGoroutines(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := &panickingStarter{
+		contextControllerStarter: newContextControllerStarter(),
+		panicFor:                 map[string]bool{"pc-panicking": true},
+	}
+	recorder := &requeueRecorder{}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	manager := newManager(dynamicClient, "test-finalizer", AdaptContextControllerStarter(starter),
+		WithClock(fakeClock),
+		WithRestartBackoff(time.Second, time.Minute),
+		WithMetricFactory(mtmetrics.NewStdMetricFactory(reg)),
+	)
+	manager.requeueAfter = recorder.requeueAfter
+
+	for _, name := range []string{"pc-healthy", "pc-panicking"} {
+		pc := createTestProviderConfig(name)
+		if err := createProviderConfigInClient(ctx, dynamicClient, pc); err != nil {
+			t.Fatalf("Failed to create ProviderConfig: %v", err)
+		}
+		if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+			t.Fatalf("Start of %s failed: %v", name, err)
+		}
+	}
+	healthyCtx, _ := starter.get("pc-healthy")
+	panickedCtx, _ := starter.get("pc-panicking")
+
+	if err := wait.PollUntilContextTimeout(ctx, 5*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		return manager.DebugTenants()["pc-panicking"].State == TenantStateDegraded, nil
+	}); err != nil {
+		t.Fatalf("Expected the tenant to become degraded after the panic: %v", err)
+	}
+	info := manager.DebugTenants()["pc-panicking"].Starters[0]
+	if info.Panics != 1 || info.LastPanic != "injected panic in pc-panicking" || info.LastPanicTime == nil {
+		t.Errorf("Expected the panic to be recorded, got %+v", info)
+	}
+	if !strings.Contains(info.LastPanicStack, "runner_test.go") {
+		t.Errorf("Expected the stack of the panic to be recorded, got %q", info.LastPanicStack)
+	}
+	if info.Ready {
+		t.Error("Expected controllers that panicked not to be ready")
+	}
+	if got := gatherMetrics(t, reg, "providerconfig_framework_tenant_panics_total", "starter")[DefaultControllerStarterName].GetCounter().GetValue(); got != 1 {
+		t.Errorf("Panics metric = %v, want 1", got)
+	}
+
+	// The requeued sync stops the controllers and restarts them after the backoff.
+	pc, err := providerConfigFromClient(ctx, dynamicClient, "pc-panicking")
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig: %v", err)
+	}
+	if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+		t.Fatalf("Sync after the panic failed: %v", err)
+	}
+	if panickedCtx.Err() == nil {
+		t.Error("Expected the context of the controllers that panicked to be cancelled")
+	}
+	degraded := conditionFromClient(ctx, t, dynamicClient, "pc-panicking", ConditionDegraded)
+	if degraded == nil || degraded.Status != metav1.ConditionTrue || degraded.Reason != ReasonControllersPanicked {
+		t.Errorf("Expected %s=True with reason %s, got %+v", ConditionDegraded, ReasonControllersPanicked, degraded)
+	}
+	if delays := recorder.get(); len(delays) == 0 || delays[len(delays)-1] != time.Second {
+		t.Errorf("Expected a requeue after the restart backoff, got %v", delays)
+	}
+
+	// The controllers exit asynchronously once their context is cancelled;
+	// until then the sync is requeued to check for their exit.
+	fakeClock.Step(time.Second)
+	if err := wait.PollUntilContextTimeout(ctx, 5*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		if err := manager.StartControllersForProviderConfig(ctx, pc); err != nil {
+			return false, err
+		}
+		return starter.startCount("pc-panicking") == 2, nil
+	}); err != nil {
+		t.Fatalf("Expected the controllers to be restarted: %v", err)
+	}
+	if state := manager.DebugTenants()["pc-panicking"].State; state != TenantStateRunning {
+		t.Errorf("Tenant state = %s after the restart, want %s", state, TenantStateRunning)
+	}
+	degraded = conditionFromClient(ctx, t, dynamicClient, "pc-panicking", ConditionDegraded)
+	if degraded == nil || degraded.Status != metav1.ConditionFalse || degraded.Reason != ReasonControllersRecovered {
+		t.Errorf("Expected %s=False with reason %s, got %+v", ConditionDegraded, ReasonControllersRecovered, degraded)
+	}
+
+	if healthyCtx.Err() != nil {
+		t.Error("Expected the controllers of the other tenant to keep running")
+	}
+	if state := manager.DebugTenants()["pc-healthy"].State; state != TenantStateRunning {
+		t.Errorf("State of the other tenant = %s, want %s", state, TenantStateRunning)
+	}
+}
+
+// errorSink is a logr.LogSink that sends the messages of logged errors to errs.
+type errorSink struct {
+	errs chan string
+}
+
+func (s *errorSink) Init(logr.RuntimeInfo)        {}
+func (s *errorSink) Enabled(int) bool             { return false }
+func (s *errorSink) Info(int, string, ...any)     {}
+func (s *errorSink) WithName(string) logr.LogSink { return s }
+func (s *errorSink) WithValues(...any) logr.LogSink {
+	return s
+}
+func (s *errorSink) Error(err error, msg string, _ ...any) {
+	s.errs <- fmt.Sprintf("%s: %v", msg, err)
+}
+
+// TestGoRecoversPanicsWithoutHandler verifies that Go recovers and logs panics
+// outside of a starter context.
+func TestGoRecoversPanicsWithoutHandler(t *testing.T) {
+	sink := &errorSink{errs: make(chan string, 1)}
+	ctx := klog.NewContext(context.Background(), logr.New(sink))
+
+	Go(ctx, func(context.Context) {
+		panic("boom")
+	})
+
+	select {
+	case msg := <-sink.errs:
+		if !strings.Contains(msg, "panic: boom") {
+			t.Errorf("Expected the panic to be logged, got %q", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the panic to be recovered and logged")
+	}
+}
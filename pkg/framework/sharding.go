@@ -0,0 +1,502 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// ShardGroupLabel is set on the membership Lease of every replica to the
+	// sharding group it belongs to.
+	ShardGroupLabel = "tenancy.gke.io/shard-group"
+	// ShardOwnerAnnotation records on a ProviderConfig the identity of the
+	// replica currently running its controllers. A replica only starts
+	// controllers for a ProviderConfig after it has claimed it through this
+	// annotation, and clears the annotation only after its controllers have
+	// stopped, so that two replicas never run the same tenant at once.
+	ShardOwnerAnnotation = "tenancy.gke.io/shard-owner"
+
+	defaultShardLeaseDuration = 15 * time.Second
+	defaultShardRenewInterval = 5 * time.Second
+	// shardVirtualNodes is the number of points each replica owns on the hash
+	// ring. More points spread tenants more evenly across replicas.
+	shardVirtualNodes = 100
+)
+
+// ShardingConfig enables sharding of ProviderConfigs across replicas of the
+// Controller. Every replica maintains its own Lease; the set of live Leases in
+// a group forms the membership, and each ProviderConfig key is assigned to one
+// member through consistent hashing.
+type ShardingConfig struct {
+	// Client is used to maintain and list the membership Leases.
+	Client coordinationv1client.LeasesGetter
+	// LeaseNamespace is the namespace of the membership Leases.
+	LeaseNamespace string
+	// Group identifies the replicas that shard ProviderConfigs among
+	// themselves. The Lease of each replica is named <Group>-<Identity>.
+	Group string
+	// Identity uniquely identifies this replica. Defaults to the hostname.
+	Identity string
+	// LeaseDuration is how long a replica is considered a member after its
+	// last renewal. It is rounded up to whole seconds. Defaults to 15s.
+	LeaseDuration time.Duration
+	// RenewInterval is how often the Lease is renewed and the membership
+	// recomputed. It must be shorter than half of LeaseDuration. Defaults to 5s.
+	RenewInterval time.Duration
+}
+
+// WithSharding makes the Controller run controllers only for the ProviderConfigs
+// assigned to this replica. When the membership changes, a replica that loses a
+// ProviderConfig stops its controllers, keeping the finalizer, before the new
+// owner starts them. Only the owner adds or removes the finalizer. WithSharding
+// and WithLeaderElection are mutually exclusive. If both are given, or cfg is
+// invalid, Run refuses to process ProviderConfigs rather than run every tenant
+// on every replica.
+func WithSharding(cfg ShardingConfig) Option {
+	return func(o *options) {
+		o.sharding = &cfg
+	}
+}
+
+// hashRing assigns keys to members through consistent hashing, so that a
+// membership change only moves the keys of the members that joined or left.
+type hashRing struct {
+	points  []uint64
+	members map[uint64]string
+}
+
+// newHashRing builds a ring with shardVirtualNodes points per member.
+func newHashRing(members []string) *hashRing {
+	r := &hashRing{members: make(map[uint64]string, len(members)*shardVirtualNodes)}
+	for _, m := range members {
+		for i := 0; i < shardVirtualNodes; i++ {
+			p := hashKey(m + "#" + strconv.Itoa(i))
+			if _, ok := r.members[p]; ok {
+				continue
+			}
+			r.members[p] = m
+			r.points = append(r.points, p)
+		}
+	}
+	slices.Sort(r.points)
+	return r
+}
+
+// owner returns the member owning key, or "" if the ring is empty.
+func (r *hashRing) owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.members[r.points[i]]
+}
+
+// hashKey hashes s onto the ring. FNV alone clusters similar strings such as
+// "replica-a#1" and "replica-b#1", so its result is passed through the
+// MurmurHash3 finalizer to spread the points.
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// sharder tracks the membership of the sharding group and the ownership of
+// ProviderConfigs by this replica.
+type sharder struct {
+	client         coordinationv1client.LeasesGetter
+	pcClient       dynamic.Interface
+	tenants        tenantResource
+	leaseNamespace string
+	group          string
+	identity       string
+	leaseDuration  time.Duration
+	renewInterval  time.Duration
+	clock          clock.WithTicker
+
+	// joined is closed once the first membership including this replica is
+	// known; until then the replica owns no ProviderConfig.
+	joined     chan struct{}
+	joinedOnce sync.Once
+
+	mu sync.RWMutex
+	// members are the identities of the live replicas, sorted.
+	members []string
+	ring    *hashRing
+	// lastRenew is when this replica last renewed its Lease.
+	lastRenew time.Time
+}
+
+// withDefaults returns cfg with the defaults applied to the unset durations.
+func (cfg ShardingConfig) withDefaults() ShardingConfig {
+	if cfg.LeaseDuration == 0 {
+		cfg.LeaseDuration = defaultShardLeaseDuration
+	}
+	if cfg.RenewInterval == 0 {
+		cfg.RenewInterval = defaultShardRenewInterval
+	}
+	return cfg
+}
+
+// validate reports missing fields and inconsistent durations of cfg, after the
+// defaults are applied.
+func (cfg ShardingConfig) validate() error {
+	if cfg.Client == nil {
+		return fmt.Errorf("sharding requires a Lease client")
+	}
+	if cfg.LeaseNamespace == "" || cfg.Group == "" {
+		return fmt.Errorf("sharding requires a Lease namespace and group")
+	}
+	cfg = cfg.withDefaults()
+	if 2*cfg.RenewInterval >= cfg.LeaseDuration {
+		return fmt.Errorf("sharding renew interval %v must be shorter than half the lease duration %v", cfg.RenewInterval, cfg.LeaseDuration)
+	}
+	return nil
+}
+
+// newSharder validates cfg and applies its defaults. The Leases are renewed
+// and checked for expiry with clk.
+func newSharder(cfg ShardingConfig, pcClient dynamic.Interface, tenants tenantResource, clk clock.WithTicker) (*sharder, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+	identity := cfg.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine sharding identity: %w", err)
+		}
+		identity = hostname
+	}
+	s := &sharder{
+		client:         cfg.Client,
+		pcClient:       pcClient,
+		tenants:        tenants,
+		leaseNamespace: cfg.LeaseNamespace,
+		group:          cfg.Group,
+		identity:       identity,
+		leaseDuration:  cfg.LeaseDuration,
+		renewInterval:  cfg.RenewInterval,
+		clock:          clk,
+		joined:         make(chan struct{}),
+		ring:           newHashRing(nil),
+	}
+	return s, nil
+}
+
+func (s *sharder) leaseName() string {
+	return s.group + "-" + s.identity
+}
+
+// owns reports whether this replica owns the given ProviderConfig key.
+func (s *sharder) owns(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ring.owner(key) == s.identity
+}
+
+// isMember reports whether identity belongs to a live replica.
+func (s *sharder) isMember(identity string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, found := slices.BinarySearch(s.members, identity)
+	return found
+}
+
+// run renews the Lease of this replica and recomputes the membership every
+// renew interval, calling onChange whenever the membership changes. When ctx
+// is cancelled, the Lease is deleted so that the other replicas take over
+// immediately. The caller must stop all controllers before cancelling ctx.
+// It logs through the logger in ctx, with the group, identity and Lease of
+// this replica.
+func (s *sharder) run(ctx context.Context, onChange func()) {
+	logger := klog.FromContext(ctx).WithValues("group", s.group, "identity", s.identity, "lease", klog.KRef(s.leaseNamespace, s.leaseName()))
+	ctx = klog.NewContext(ctx, logger)
+	ticker := s.clock.NewTicker(s.renewInterval)
+	defer ticker.Stop()
+	for {
+		s.refresh(ctx, onChange)
+		select {
+		case <-ctx.Done():
+			s.deleteLease(ctx)
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// refresh renews the Lease and recomputes the membership once.
+func (s *sharder) refresh(ctx context.Context, onChange func()) {
+	logger := klog.FromContext(ctx)
+	now := s.clock.Now()
+	if err := s.renewLease(ctx, now); err != nil {
+		logger.Error(err, "Failed to renew shard Lease")
+	} else {
+		s.mu.Lock()
+		s.lastRenew = now
+		s.mu.Unlock()
+	}
+
+	var members []string
+	s.mu.RLock()
+	// Give up the ProviderConfigs well before the other replicas consider the
+	// Lease expired, so that controllers are stopped before they take over.
+	renewed := s.clock.Since(s.lastRenew) < s.leaseDuration/2
+	s.mu.RUnlock()
+	if renewed {
+		var err error
+		members, err = s.listMembers(ctx, now)
+		if err != nil {
+			logger.Error(err, "Failed to list shard Leases", "namespace", s.leaseNamespace)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	changed := !slices.Equal(members, s.members)
+	if changed {
+		s.members = members
+		s.ring = newHashRing(members)
+	}
+	s.mu.Unlock()
+	if changed {
+		logger.Info("Shard membership changed", "members", members)
+		onChange()
+	}
+	if slices.Contains(members, s.identity) {
+		s.joinedOnce.Do(func() { close(s.joined) })
+	}
+}
+
+// renewLease creates or renews the Lease of this replica.
+func (s *sharder) renewLease(ctx context.Context, now time.Time) error {
+	leases := s.client.Leases(s.leaseNamespace)
+	durationSeconds := int32((s.leaseDuration + time.Second - 1) / time.Second)
+	renewTime := metav1.NewMicroTime(now)
+	lease, err := leases.Get(ctx, s.leaseName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: s.leaseNamespace,
+				Name:      s.leaseName(),
+				Labels:    map[string]string{ShardGroupLabel: s.group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &s.identity,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &renewTime,
+				RenewTime:            &renewTime,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.HolderIdentity = &s.identity
+	lease.Spec.LeaseDurationSeconds = &durationSeconds
+	lease.Spec.RenewTime = &renewTime
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// listMembers returns the sorted identities of the replicas whose Lease has
+// not expired.
+func (s *sharder) listMembers(ctx context.Context, now time.Time) ([]string, error) {
+	list, err := s.client.Leases(s.leaseNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: ShardGroupLabel + "=" + s.group,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var members []string
+	for _, lease := range list.Items {
+		spec := lease.Spec
+		if spec.HolderIdentity == nil || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expiry := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+		if *spec.HolderIdentity != s.identity && !now.Before(expiry) {
+			continue
+		}
+		members = append(members, *spec.HolderIdentity)
+	}
+	slices.Sort(members)
+	return slices.Compact(members), nil
+}
+
+// deleteLease removes the Lease of this replica. It runs after ctx is
+// cancelled and only uses its values, e.g. the logger.
+func (s *sharder) deleteLease(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.renewInterval)
+	defer cancel()
+	err := s.client.Leases(s.leaseNamespace).Delete(ctx, s.leaseName(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.FromContext(ctx).Error(err, "Failed to delete shard Lease")
+	}
+}
+
+// shardOwnerPatch returns a JSON merge patch that sets the ShardOwnerAnnotation
+// of obj to owner, or removes it if owner is empty. Like finalizerPatch, the
+// patch carries the resourceVersion of obj as a precondition, so the API
+// server rejects it with a conflict if obj changed since it was read.
+func shardOwnerPatch(obj *unstructured.Unstructured, owner string) ([]byte, error) {
+	var value any
+	if owner != "" {
+		value = owner
+	}
+	return json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations":     map[string]any{ShardOwnerAnnotation: value},
+			"resourceVersion": obj.GetResourceVersion(),
+		},
+	})
+}
+
+// patchShardOwner sets the ShardOwnerAnnotation of obj to owner, or removes it
+// if owner is empty, and returns the patched object.
+func (s *sharder) patchShardOwner(ctx context.Context, obj *unstructured.Unstructured, owner string) (*unstructured.Unstructured, error) {
+	patch, err := shardOwnerPatch(obj, owner)
+	if err != nil {
+		return nil, err
+	}
+	return s.tenants.resource(s.pcClient, obj).Patch(ctx, obj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+}
+
+// claim records this replica as the owner of the ProviderConfig and returns
+// the updated object. The patch fails with a conflict if another replica
+// changed the ProviderConfig concurrently.
+func (s *sharder) claim(ctx context.Context, pc *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	return s.patchShardOwner(ctx, pc, s.identity)
+}
+
+// release clears the claim of this replica on the ProviderConfig, if it holds one.
+func (s *sharder) release(ctx context.Context, pc *unstructured.Unstructured) error {
+	latest, err := s.tenants.resource(s.pcClient, pc).Get(ctx, pc.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if latest.GetAnnotations()[ShardOwnerAnnotation] != s.identity {
+		return nil
+	}
+	_, err = s.patchShardOwner(ctx, latest, "")
+	return err
+}
+
+// runWithSharding runs the ProviderConfig workers while taking part in the
+// sharding group. It returns once the stop channel is closed, after the
+// controllers of every tenant have been stopped and the Lease of this replica
+// has been deleted.
+//
+// The workers only start once the first membership is known: before that, the
+// replica owns nothing and would hand off every ProviderConfig it claimed
+// before a restart, only to claim it again.
+func (c *Controller) runWithSharding() {
+	ctx, cancel := context.WithCancel(context.Background())
+	membershipDone := make(chan struct{})
+	go func() {
+		defer close(membershipDone)
+		c.sharder.run(ctx, c.enqueueAll)
+	}()
+
+	select {
+	case <-c.sharder.joined:
+	case <-c.stopCh:
+		cancel()
+		<-membershipDone
+		return
+	}
+	c.providerConfigQueue.Run()
+	klog.InfoS("Started ProviderConfig Controller in shard group", "numWorkers", c.workersCount, "group", c.sharder.group, "identity", c.sharder.identity)
+	go c.runDriftReconciler(c.stopCh)
+	<-c.stopCh
+
+	// Drain the workers first so that no tenant is started concurrently, then
+	// stop every tenant before leaving the group so that the other replicas
+	// only take over once the controllers have stopped.
+	c.providerConfigQueue.Shutdown()
+	c.stopAllTenants("leaving shard group")
+	cancel()
+	<-membershipDone
+}
+
+// enqueueAll enqueues every known ProviderConfig, e.g. to re-evaluate
+// ownership after the shard membership changed.
+func (c *Controller) enqueueAll() {
+	for _, key := range c.providerConfigLister.ListKeys() {
+		c.providerConfigQueue.Enqueue(cache.ExplicitKey(key))
+	}
+}
+
+// syncShardOwnership makes sure that this replica only runs controllers for the
+// ProviderConfigs it owns. It returns the ProviderConfig to continue syncing
+// with, or nil if the sync is complete.
+func (c *Controller) syncShardOwnership(ctx context.Context, pc *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	key := c.tenants.key(pc)
+	claimedBy := pc.GetAnnotations()[ShardOwnerAnnotation]
+	logger := klog.FromContext(ctx)
+
+	if !c.sharder.owns(key) {
+		// Stop our controllers, if any, and only then give up the claim so that
+		// the new owner can start its own. While they are stopping, the
+		// ProviderConfig is requeued to check again.
+		if !c.manager.ReleaseControllersForProviderConfig(ctx, pc) {
+			return nil, nil
+		}
+		if claimedBy == c.sharder.identity {
+			if err := c.sharder.release(ctx, pc); err != nil {
+				return nil, fmt.Errorf("failed to release shard claim on providerConfig %s: %w", key, err)
+			}
+			logger.Info("Handed off providerConfig to another replica")
+		}
+		return nil, nil
+	}
+
+	if claimedBy != "" && claimedBy != c.sharder.identity && c.sharder.isMember(claimedBy) {
+		// The previous owner is still alive and has not stopped its controllers yet.
+		logger.Info("Waiting for previous owner to release providerConfig", "owner", claimedBy)
+		c.providerConfigQueue.EnqueueAfter(cache.ExplicitKey(key), c.sharder.renewInterval)
+		return nil, nil
+	}
+	if claimedBy == c.sharder.identity || !pc.GetDeletionTimestamp().IsZero() {
+		return pc, nil
+	}
+	claimed, err := c.sharder.claim(ctx, pc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim providerConfig %s: %w", key, err)
+	}
+	logger.Info("Claimed providerConfig", "previousOwner", claimedBy)
+	return claimed, nil
+}
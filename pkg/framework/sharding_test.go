@@ -0,0 +1,485 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+)
+
+// TestHashRingMovesOnlyKeysOfNewMember verifies that adding a member to the
+// ring only moves keys to the new member.
+func TestHashRingMovesOnlyKeysOfNewMember(t *testing.T) {
+	before := newHashRing([]string{"replica-a", "replica-b", "replica-c"})
+	after := newHashRing([]string{"replica-a", "replica-b", "replica-c", "replica-d"})
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("tenant-%d", i)
+		oldOwner, newOwner := before.owner(key), after.owner(key)
+		if oldOwner != newOwner && newOwner != "replica-d" {
+			t.Errorf("Key %s moved from %s to %s, expected it to move only to the new member", key, oldOwner, newOwner)
+		}
+		if oldOwner != before.owner(key) {
+			t.Errorf("Owner of key %s is not deterministic", key)
+		}
+		counts[newOwner]++
+	}
+	for _, member := range []string{"replica-a", "replica-b", "replica-c", "replica-d"} {
+		if counts[member] < 100 {
+			t.Errorf("Member %s owns %d of 1000 keys, expected a more even spread: %v", member, counts[member], counts)
+		}
+	}
+	if owner := newHashRing(nil).owner("tenant"); owner != "" {
+		t.Errorf("Expected empty ring to have no owner, got %q", owner)
+	}
+}
+
+// TestSharderMembership verifies that replicas discover each other through
+// their Leases, ignore expired Leases, and split keys between them.
+func TestSharderMembership(t *testing.T) {
+	ctx := context.Background()
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	client := k8sfake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testLeaseNamespace,
+			Name:      "shards-replica-expired",
+			Labels:    map[string]string{ShardGroupLabel: "shards"},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To("replica-expired"),
+			LeaseDurationSeconds: ptr.To[int32](1),
+			RenewTime:            &metav1.MicroTime{Time: fakeClock.Now().Add(-time.Minute)},
+		},
+	})
+
+	newTestSharder := func(identity string) *sharder {
+		s, err := newSharder(ShardingConfig{
+			Client:         client.CoordinationV1(),
+			LeaseNamespace: testLeaseNamespace,
+			Group:          "shards",
+			Identity:       identity,
+		}, nil, defaultTenantResource(), fakeClock)
+		if err != nil {
+			t.Fatalf("newSharder(%s) failed: %v", identity, err)
+		}
+		return s
+	}
+	a, b := newTestSharder("replica-a"), newTestSharder("replica-b")
+
+	changes := 0
+	onChange := func() { changes++ }
+	a.refresh(ctx, onChange)
+	b.refresh(ctx, onChange)
+	a.refresh(ctx, onChange)
+
+	if !a.isMember("replica-b") || !b.isMember("replica-a") {
+		t.Fatalf("Expected replicas to see each other, got %v and %v", a.members, b.members)
+	}
+	if a.isMember("replica-expired") {
+		t.Errorf("Did not expect replica with expired Lease to be a member")
+	}
+	if changes != 3 {
+		t.Errorf("Expected 3 membership changes, got %d", changes)
+	}
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("tenant-%d", i)
+		if a.owns(key) == b.owns(key) {
+			t.Errorf("Expected exactly one replica to own %s", key)
+		}
+	}
+
+	// A replica that cannot renew its Lease gives up all keys.
+	fakeClock.Step(a.leaseDuration)
+	failingClient := k8sfake.NewSimpleClientset()
+	failingClient.PrependReactor("*", "leases", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("injected error")
+	})
+	a.client = failingClient.CoordinationV1()
+	a.refresh(ctx, onChange)
+	if a.owns("tenant-0") || a.owns("tenant-1") {
+		t.Errorf("Expected a replica that cannot renew its Lease to own nothing")
+	}
+}
+
+// newShardedTestController returns a controller whose sharder sees the given
+// members, and the name of a ProviderConfig owned by each member.
+func newShardedTestController(t *testing.T, identity string, members ...string) (*testProviderConfigController, map[string]string) {
+	t.Helper()
+	tc := newTestProviderConfigController(t)
+	s, err := newSharder(ShardingConfig{
+		Client:         k8sfake.NewSimpleClientset().CoordinationV1(),
+		LeaseNamespace: testLeaseNamespace,
+		Group:          "shards",
+		Identity:       identity,
+		RenewInterval:  time.Hour,
+		LeaseDuration:  3 * time.Hour,
+	}, tc.pcClient, defaultTenantResource(), testingclock.NewFakeClock(time.Now()))
+	if err != nil {
+		t.Fatalf("newSharder failed: %v", err)
+	}
+	s.members = members
+	s.ring = newHashRing(members)
+	tc.pcController.sharder = s
+
+	owned := map[string]string{}
+	for i := 0; len(owned) < len(members); i++ {
+		name := fmt.Sprintf("pc-%d", i)
+		if owner := s.ring.owner(name); owned[owner] == "" {
+			owned[owner] = name
+		}
+	}
+	return tc, owned
+}
+
+func shardOwner(t *testing.T, tc *testProviderConfigController, name string) string {
+	t.Helper()
+	pc, err := tc.pcClient.Resource(testProviderConfigGVR).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig %s: %v", name, err)
+	}
+	return pc.GetAnnotations()[ShardOwnerAnnotation]
+}
+
+func shardedProviderConfig(name, owner string) *unstructured.Unstructured {
+	pc := testLeaderElectionProviderConfig(name)
+	if owner != "" {
+		pc.SetAnnotations(map[string]string{ShardOwnerAnnotation: owner})
+	}
+	return pc
+}
+
+// TestSyncShardOwnership verifies that a replica claims and starts the
+// ProviderConfigs it owns, waits for live previous owners to release them, and
+// hands off the ones it no longer owns.
+func TestSyncShardOwnership(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		owner       string
+		claimedBy   string
+		releasing   bool
+		wantStarted bool
+		wantRelease bool
+		wantClaim   string
+	}{
+		{
+			desc:        "owned and unclaimed ProviderConfig is claimed and started",
+			owner:       "replica-a",
+			wantStarted: true,
+			wantClaim:   "replica-a",
+		},
+		{
+			desc:        "owned ProviderConfig claimed by a departed replica is taken over",
+			owner:       "replica-a",
+			claimedBy:   "replica-gone",
+			wantStarted: true,
+			wantClaim:   "replica-a",
+		},
+		{
+			desc:      "owned ProviderConfig claimed by a live replica waits for the handoff",
+			owner:     "replica-a",
+			claimedBy: "replica-b",
+			wantClaim: "replica-b",
+		},
+		{
+			desc:        "ProviderConfig owned by another replica is released",
+			owner:       "replica-b",
+			claimedBy:   "replica-a",
+			wantRelease: true,
+		},
+		{
+			desc:        "ProviderConfig owned by another replica keeps the claim while its controllers stop",
+			owner:       "replica-b",
+			claimedBy:   "replica-a",
+			releasing:   true,
+			wantRelease: true,
+			wantClaim:   "replica-a",
+		},
+		{
+			desc:        "ProviderConfig owned and claimed by another replica is left alone",
+			owner:       "replica-b",
+			claimedBy:   "replica-b",
+			wantRelease: true,
+			wantClaim:   "replica-b",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctrl, owned := newShardedTestController(t, "replica-a", "replica-a", "replica-b")
+			ctrl.manager.releasing = tc.releasing
+			name := owned[tc.owner]
+			addProviderConfig(t, ctrl, shardedProviderConfig(name, tc.claimedBy))
+
+			if err := ctrl.pcController.sync(context.Background(), name); err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+
+			if got := ctrl.manager.HasStarted(name); got != tc.wantStarted {
+				t.Errorf("HasStarted(%s) = %v, want %v", name, got, tc.wantStarted)
+			}
+			if got := ctrl.manager.HasReleased(name); got != tc.wantRelease {
+				t.Errorf("HasReleased(%s) = %v, want %v", name, got, tc.wantRelease)
+			}
+			if got := shardOwner(t, ctrl, name); got != tc.wantClaim {
+				t.Errorf("Shard owner of %s = %q, want %q", name, got, tc.wantClaim)
+			}
+			if ctrl.manager.HasStopped(name) {
+				t.Errorf("Did not expect finalizer handling for %s", name)
+			}
+		})
+	}
+}
+
+// TestSharderRenewsLeaseOnTick verifies that a running sharder renews its Lease
+// on every tick of its clock and deletes it when stopped.
+func TestSharderRenewsLeaseOnTick(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	client := k8sfake.NewSimpleClientset()
+	s, err := newSharder(ShardingConfig{
+		Client:         client.CoordinationV1(),
+		LeaseNamespace: testLeaseNamespace,
+		Group:          "shards",
+		Identity:       "replica-a",
+		RenewInterval:  time.Minute,
+		LeaseDuration:  3 * time.Minute,
+	}, nil, defaultTenantResource(), fakeClock)
+	if err != nil {
+		t.Fatalf("newSharder failed: %v", err)
+	}
+
+	renewTime := func() time.Time {
+		lease, err := client.CoordinationV1().Leases(testLeaseNamespace).Get(context.TODO(), s.leaseName(), metav1.GetOptions{})
+		if err != nil || lease.Spec.RenewTime == nil {
+			return time.Time{}
+		}
+		return lease.Spec.RenewTime.Time
+	}
+	waitFor := func(desc string, cond func() bool) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for !cond() {
+			select {
+			case <-deadline:
+				t.Fatalf("Timed out waiting for %s", desc)
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.run(ctx, func() {})
+	}()
+
+	start := fakeClock.Now()
+	waitFor("the initial renewal", func() bool { return renewTime().Equal(start) })
+	waitFor("the renew ticker", fakeClock.HasWaiters)
+	fakeClock.Step(time.Minute)
+	waitFor("the renewal on tick", func() bool { return renewTime().Equal(start.Add(time.Minute)) })
+
+	cancel()
+	<-done
+	if _, err := client.CoordinationV1().Leases(testLeaseNamespace).Get(context.TODO(), s.leaseName(), metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected the Lease to be deleted once the sharder stopped")
+	}
+}
+
+// TestSharderClaimPatchesWithResourceVersion verifies that claims and releases
+// patch the shard owner annotation with the resourceVersion they were based on,
+// so that a concurrent change fails them with a conflict.
+func TestSharderClaimPatchesWithResourceVersion(t *testing.T) {
+	ctx := context.Background()
+	ctrl, owned := newShardedTestController(t, "replica-a", "replica-a")
+	name := owned["replica-a"]
+	unclaimed := shardedProviderConfig(name, "")
+	unclaimed.SetResourceVersion("7")
+	addProviderConfig(t, ctrl, unclaimed)
+	pc, err := ctrl.pcClient.Resource(testProviderConfigGVR).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get ProviderConfig %s: %v", name, err)
+	}
+	conflicts := injectPatchConflicts(ctrl.pcClient.(*fake.FakeDynamicClient), 1)
+	s := ctrl.pcController.sharder
+
+	if _, err := s.claim(ctx, pc); err == nil {
+		t.Fatalf("Expected claim to fail with the injected conflict")
+	}
+	if got := shardOwner(t, ctrl, name); got != "" {
+		t.Errorf("Shard owner of %s after a conflict = %q, want none", name, got)
+	}
+	claimed, err := s.claim(ctx, pc)
+	if err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if got := claimed.GetAnnotations()[ShardOwnerAnnotation]; got != "replica-a" {
+		t.Errorf("Claimed ProviderConfig has shard owner %q, want %q", got, "replica-a")
+	}
+	if err := s.release(ctx, claimed); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if got := shardOwner(t, ctrl, name); got != "" {
+		t.Errorf("Shard owner of %s after release = %q, want none", name, got)
+	}
+
+	patches := conflicts.get()
+	if len(patches) != 3 {
+		t.Fatalf("Expected 3 patches, got %d: %v", len(patches), patches)
+	}
+	for i, patch := range patches {
+		if got := patchResourceVersion(patch); got != "7" {
+			t.Errorf("Patch %d has resourceVersion %q, want %q", i, got, "7")
+		}
+	}
+}
+
+// TestInvalidShardingRefusesToRun verifies that the Controller does not process
+// any ProviderConfig, instead of running every tenant on every replica, if the
+// sharding configuration is invalid or combined with leader election.
+func TestInvalidShardingRefusesToRun(t *testing.T) {
+	leaseClient := k8sfake.NewSimpleClientset().CoordinationV1()
+	testCases := []struct {
+		desc string
+		opts []Option
+	}{
+		{
+			desc: "missing Lease client",
+			opts: []Option{WithSharding(ShardingConfig{LeaseNamespace: testLeaseNamespace, Group: "shards"})},
+		},
+		{
+			desc: "sharding with leader election",
+			opts: []Option{
+				WithSharding(ShardingConfig{Client: leaseClient, LeaseNamespace: testLeaseNamespace, Group: "shards"}),
+				WithLeaderElection(LeaderElectionConfig{Client: leaseClient, LeaseNamespace: testLeaseNamespace, LeaseName: "leader"}),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := indexer.Add(createTestProviderConfig("pc-1")); err != nil {
+				t.Fatalf("Failed to add ProviderConfig to indexer: %v", err)
+			}
+			starter := newMockControllerStarter()
+			ctrl := New(dynamicClient, &fakeInformer{Indexer: indexer, synced: true}, "test-finalizer", starter, make(chan struct{}), tc.opts...)
+			ctrl.providerConfigQueue.Enqueue(cache.ExplicitKey("pc-1"))
+
+			runDone := make(chan struct{})
+			go func() {
+				defer close(runDone)
+				ctrl.Run()
+			}()
+			select {
+			case <-runDone:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Expected Run to return right away")
+			}
+			if ctrl.sharder != nil {
+				t.Errorf("Did not expect a sharder to be set up")
+			}
+			if got := starter.getStartCallCount(); got != 0 {
+				t.Errorf("Expected no controllers to be started, got %d starts", got)
+			}
+		})
+	}
+}
+
+// TestShardingWaitsForMembershipBeforeSyncing verifies that the workers only
+// start once the membership is known, so that a restarted replica keeps the
+// claims it made before instead of handing off every ProviderConfig.
+func TestShardingWaitsForMembershipBeforeSyncing(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	leaseClient := k8sfake.NewSimpleClientset()
+	var listCalls atomic.Int32
+	var listFails atomic.Bool
+	listFails.Store(true)
+	leaseClient.PrependReactor("list", "leases", func(k8stesting.Action) (bool, runtime.Object, error) {
+		listCalls.Add(1)
+		if listFails.Load() {
+			return true, nil, errors.New("injected error")
+		}
+		return false, nil, nil
+	})
+
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	patches := injectPatchConflicts(dynamicClient, 0)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pc := shardedProviderConfig("pc-claimed", "replica-a")
+	if err := createProviderConfigInClient(context.TODO(), dynamicClient, pc); err != nil {
+		t.Fatalf("Failed to create ProviderConfig: %v", err)
+	}
+	if err := indexer.Add(pc); err != nil {
+		t.Fatalf("Failed to add ProviderConfig to indexer: %v", err)
+	}
+	starter := newMockControllerStarter()
+	stopCh := make(chan struct{})
+	ctrl := New(dynamicClient, &fakeInformer{Indexer: indexer, synced: true}, "test-finalizer", starter, stopCh,
+		WithClock(fakeClock),
+		WithDriftReconcileInterval(0),
+		WithSharding(ShardingConfig{
+			Client:         leaseClient.CoordinationV1(),
+			LeaseNamespace: testLeaseNamespace,
+			Group:          "shards",
+			Identity:       "replica-a",
+			RenewInterval:  time.Minute,
+			LeaseDuration:  3 * time.Minute,
+		}),
+	)
+	ctrl.providerConfigQueue.Enqueue(cache.ExplicitKey("pc-claimed"))
+
+	runDone := make(chan struct{})
+	go func() {
+		defer close(runDone)
+		ctrl.Run()
+	}()
+	defer func() {
+		close(stopCh)
+		<-runDone
+	}()
+	waitFor := func(desc string, cond func() bool) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for !cond() {
+			select {
+			case <-deadline:
+				t.Fatalf("Timed out waiting for %s", desc)
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}
+
+	waitFor("the first membership refresh", func() bool { return listCalls.Load() > 0 })
+	if got := ctrl.providerConfigQueue.Len(); got != 1 {
+		t.Errorf("Expected the ProviderConfig to stay queued until the membership is known, got queue length %d", got)
+	}
+
+	listFails.Store(false)
+	waitFor("the renew ticker", fakeClock.HasWaiters)
+	fakeClock.Step(time.Minute)
+	waitFor("the controllers to start", func() bool { return starter.getStartCallCount() == 1 })
+
+	for _, patch := range patches.get() {
+		metadata, _ := patch["metadata"].(map[string]any)
+		if annotations, ok := metadata["annotations"].(map[string]any); ok {
+			if owner, ok := annotations[ShardOwnerAnnotation]; ok && owner != "replica-a" {
+				t.Errorf("Expected the claim of replica-a to be kept, got patch %v", patch)
+			}
+		}
+	}
+}
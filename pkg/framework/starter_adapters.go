@@ -0,0 +1,163 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// AdaptControllerStarter returns a ContextControllerStarter that runs the
+// controllers of a channel-based ControllerStarter. The stop channel returned
+// by the starter is closed once the context is cancelled. If the starter
+// implements HandleControllerStarter, its Done channel is passed through, and
+// its Ready channel is reported through ReadinessReporter.
+func AdaptControllerStarter(starter ControllerStarter) ContextControllerStarter {
+	a := &channelStarterAdapter{starter: starter, ready: map[types.NamespacedName]<-chan struct{}{}}
+	if _, ok := starter.(HandleControllerStarter); ok {
+		return &handleStarterAdapter{channelStarterAdapter: a}
+	}
+	return a
+}
+
+// channelStarterAdapter adapts a ControllerStarter to ContextControllerStarter.
+type channelStarterAdapter struct {
+	starter ControllerStarter
+
+	// mu guards ready, the Ready channels of the handles of the running
+	// controllers by ProviderConfig.
+	mu    sync.Mutex
+	ready map[types.NamespacedName]<-chan struct{}
+}
+
+// StartControllerWithContext implements ContextControllerStarter.
+func (a *channelStarterAdapter) StartControllerWithContext(ctx context.Context, pc *unstructured.Unstructured) (<-chan struct{}, error) {
+	var handle *ControllerHandle
+	if hs, ok := a.starter.(HandleControllerStarter); ok {
+		h, err := hs.StartControllerWithHandle(pc)
+		if err != nil {
+			return nil, err
+		}
+		handle = h
+	} else {
+		stopCh, err := a.starter.StartController(pc)
+		if err != nil {
+			return nil, err
+		}
+		handle = &ControllerHandle{StopCh: stopCh}
+	}
+	if handle == nil || handle.StopCh == nil {
+		return nil, fmt.Errorf("controller starter returned nil channel")
+	}
+	if ready := handle.Ready; ready != nil {
+		key := types.NamespacedName{Namespace: pc.GetNamespace(), Name: pc.GetName()}
+		a.mu.Lock()
+		a.ready[key] = ready
+		a.mu.Unlock()
+		context.AfterFunc(ctx, func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			// A restart may have replaced the channel already.
+			if a.ready[key] == ready {
+				delete(a.ready, key)
+			}
+		})
+	}
+	stopCh := handle.StopCh
+	context.AfterFunc(ctx, func() { close(stopCh) })
+	return handle.Done, nil
+}
+
+// StartController implements ControllerStarter by delegating to the adapted starter.
+func (a *channelStarterAdapter) StartController(pc *unstructured.Unstructured) (chan<- struct{}, error) {
+	return a.starter.StartController(pc)
+}
+
+// adapted returns the adapted starter.
+func (a *channelStarterAdapter) adapted() any {
+	return a.starter
+}
+
+// handleStarterAdapter is the channelStarterAdapter of a HandleControllerStarter,
+// which reports the Ready channels of the handles.
+type handleStarterAdapter struct {
+	*channelStarterAdapter
+}
+
+// HasSynced implements ReadinessReporter. The controllers are ready once the
+// Ready channel of their handle, if any, is closed and the adapted starter, if
+// it implements ReadinessReporter, reports them ready.
+func (a *handleStarterAdapter) HasSynced(pc *unstructured.Unstructured) bool {
+	a.mu.Lock()
+	ready := a.ready[types.NamespacedName{Namespace: pc.GetNamespace(), Name: pc.GetName()}]
+	a.mu.Unlock()
+	if ready != nil {
+		select {
+		case <-ready:
+		default:
+			return false
+		}
+	}
+	if rr, ok := a.starter.(ReadinessReporter); ok {
+		return rr.HasSynced(pc)
+	}
+	return true
+}
+
+// AdaptContextControllerStarter returns a ControllerStarter for a starter that
+// only implements ContextControllerStarter, so that it can be passed to New or
+// WithNamedControllerStarter. The framework calls StartControllerWithContext
+// on the returned starter; StartController runs the controllers with a context
+// that is cancelled once the returned stop channel is closed.
+func AdaptContextControllerStarter(starter ContextControllerStarter) ControllerStarter {
+	return &contextStarterAdapter{starter: starter}
+}
+
+// contextStarterAdapter adapts a ContextControllerStarter to ControllerStarter.
+type contextStarterAdapter struct {
+	starter ContextControllerStarter
+}
+
+// StartControllerWithContext implements ContextControllerStarter by delegating
+// to the adapted starter.
+func (a *contextStarterAdapter) StartControllerWithContext(ctx context.Context, pc *unstructured.Unstructured) (<-chan struct{}, error) {
+	return a.starter.StartControllerWithContext(ctx, pc)
+}
+
+// StartController implements ControllerStarter.
+func (a *contextStarterAdapter) StartController(pc *unstructured.Unstructured) (chan<- struct{}, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := a.starter.StartControllerWithContext(ctx, pc); err != nil {
+		cancel()
+		return nil, err
+	}
+	stopCh := make(chan struct{})
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	return stopCh, nil
+}
+
+// adapted returns the adapted starter.
+func (a *contextStarterAdapter) adapted() any {
+	return a.starter
+}
+
+// readinessReporterOf returns the ReadinessReporter implemented by starter or,
+// if starter is an adapter, by the starter it adapts.
+func readinessReporterOf(starter any) (ReadinessReporter, bool) {
+	for starter != nil {
+		if rr, ok := starter.(ReadinessReporter); ok {
+			return rr, true
+		}
+		adapter, ok := starter.(interface{ adapted() any })
+		if !ok {
+			break
+		}
+		starter = adapter.adapted()
+	}
+	return nil, false
+}
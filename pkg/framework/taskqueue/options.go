@@ -0,0 +1,193 @@
+package taskqueue
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// Defaults of the rate limiter, matching workqueue.DefaultControllerRateLimiter.
+	defaultBaseDelay = 5 * time.Millisecond
+	defaultMaxDelay  = 1000 * time.Second
+	defaultQPS       = 10
+	defaultBurst     = 100
+)
+
+// Option configures a PeriodicTaskQueueWithMultipleWorkers.
+type Option func(*options)
+
+// options holds the tunables of a task queue. Invalid values passed to an
+// Option are recorded in errs and make queue construction fail.
+type options struct {
+	// rateLimiter replaces the rate limiter built from the parameters below.
+	rateLimiter workqueue.RateLimiter
+	// baseDelay and maxDelay bound the per-item exponential backoff.
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	// qps and burst configure the overall token bucket.
+	qps   float64
+	burst int
+	// maxRequeues is how many times a failing key is retried before it is
+	// dropped. Zero retries forever.
+	maxRequeues int
+	clock       clock.WithTicker
+	// clockSet and keyFuncSet record whether the clock and the key function
+	// were set, see SetsClock and SetsKeyFunc.
+	clockSet   bool
+	keyFuncSet bool
+	// priorities orders keys by Priority instead of FIFO.
+	priorities bool
+	// keyFunc translates an object to its key.
+	keyFunc func(obj any) (string, error)
+	// logger is the logger the workers derive their loggers from.
+	logger klog.Logger
+
+	errs []error
+}
+
+// newOptions returns the default options with opts applied in order.
+func newOptions(opts ...Option) options {
+	o := options{
+		baseDelay: defaultBaseDelay,
+		maxDelay:  defaultMaxDelay,
+		qps:       defaultQPS,
+		burst:     defaultBurst,
+		clock:     clock.RealClock{},
+		keyFunc:   KeyFunc,
+		logger:    klog.Background(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ValidateOptions reports the invalid values passed to opts, if any.
+func ValidateOptions(opts ...Option) error {
+	return errors.Join(newOptions(opts...).errs...)
+}
+
+// SetsKeyFunc reports whether opts include WithKeyFunc, so that callers that
+// own the key function of a queue can reject options overriding it.
+func SetsKeyFunc(opts ...Option) bool {
+	return newOptions(opts...).keyFuncSet
+}
+
+// SetsClock reports whether opts include WithClock, so that callers that own
+// the clock of a queue can reject options overriding it.
+func SetsClock(opts ...Option) bool {
+	return newOptions(opts...).clockSet
+}
+
+// buildRateLimiter returns the rate limiter configured by o.
+func (o options) buildRateLimiter() workqueue.RateLimiter {
+	if o.rateLimiter != nil {
+		return o.rateLimiter
+	}
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(o.baseDelay, o.maxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(o.qps), o.burst)},
+	)
+}
+
+// WithRateLimiter replaces the default rate limiter of the queue. It takes
+// precedence over WithItemBackoff and WithOverallRateLimit.
+func WithRateLimiter(rateLimiter workqueue.RateLimiter) Option {
+	return func(o *options) {
+		if rateLimiter == nil {
+			o.errs = append(o.errs, errors.New("rate limiter must not be nil"))
+			return
+		}
+		o.rateLimiter = rateLimiter
+	}
+}
+
+// WithItemBackoff sets the exponential backoff applied to a key after each
+// failed sync. The delay starts at base and doubles up to max. The defaults
+// are 5ms and 1000s.
+func WithItemBackoff(base, max time.Duration) Option {
+	return func(o *options) {
+		if base <= 0 || max < base {
+			o.errs = append(o.errs, fmt.Errorf("item backoff requires 0 < base <= max, got base %v and max %v", base, max))
+			return
+		}
+		o.baseDelay = base
+		o.maxDelay = max
+	}
+}
+
+// WithOverallRateLimit limits how fast failed keys are retried across all keys
+// with a token bucket of the given rate and size. The defaults are 10 qps and
+// a burst of 100.
+func WithOverallRateLimit(qps float64, burst int) Option {
+	return func(o *options) {
+		if qps <= 0 || burst <= 0 {
+			o.errs = append(o.errs, fmt.Errorf("overall rate limit requires positive qps and burst, got %v and %d", qps, burst))
+			return
+		}
+		o.qps = qps
+		o.burst = burst
+	}
+}
+
+// WithMaxRequeues drops a key after it failed to sync n times in a row, instead
+// of retrying it forever. The key is synced again the next time it is
+// enqueued. Zero, the default, retries forever.
+func WithMaxRequeues(n int) Option {
+	return func(o *options) {
+		if n < 0 {
+			o.errs = append(o.errs, fmt.Errorf("max requeues must not be negative, got %d", n))
+			return
+		}
+		o.maxRequeues = n
+	}
+}
+
+// WithClock sets the clock used to delay keys, e.g. a fake clock in tests.
+func WithClock(c clock.WithTicker) Option {
+	return func(o *options) {
+		if c == nil {
+			o.errs = append(o.errs, errors.New("clock must not be nil"))
+			return
+		}
+		o.clock = c
+		o.clockSet = true
+	}
+}
+
+// WithPriorities makes workers pick up keys by Priority instead of in FIFO
+// order. Keys of the same priority are still picked up in FIFO order, and
+// keys that failed to sync are retried with at least PriorityNew.
+func WithPriorities() Option {
+	return func(o *options) {
+		o.priorities = true
+	}
+}
+
+// WithKeyFunc sets how objects passed to the queue are translated to keys. The
+// default is KeyFunc.
+func WithKeyFunc(keyFunc func(obj any) (string, error)) Option {
+	return func(o *options) {
+		if keyFunc == nil {
+			o.errs = append(o.errs, errors.New("key function must not be nil"))
+			return
+		}
+		o.keyFunc = keyFunc
+		o.keyFuncSet = true
+	}
+}
+
+// WithLogger sets the logger the workers log with. Each worker adds its
+// worker ID and passes the logger to the sync function through its context
+// (see klog.FromContext). The default is klog.Background().
+func WithLogger(logger klog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
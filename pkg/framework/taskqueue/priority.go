@@ -0,0 +1,131 @@
+package taskqueue
+
+import (
+	"slices"
+	"sync"
+)
+
+// Priority orders keys in a queue created with WithPriorities. Workers pick up
+// keys of a higher priority first and keys of the same priority in FIFO order.
+type Priority int
+
+const (
+	// PriorityRoutine is for keys that most likely need no work, such as
+	// resyncs and no-op updates. It is the priority of keys added by Enqueue.
+	PriorityRoutine Priority = iota
+	// PriorityNew is for keys that need work to reach their desired state,
+	// such as new objects and retries of failed syncs.
+	PriorityNew
+	// PriorityTerminating is for keys of objects that are being deleted.
+	PriorityTerminating
+
+	numPriorities = int(PriorityTerminating) + 1
+)
+
+// priorityQueue is a workqueue.Queue that pops keys by priority. The workqueue
+// calls Push, Touch, Pop and Len under its own lock; the priority a key is
+// added with is passed beforehand through request, which may be called
+// concurrently, so the priorityQueue guards its state with its own mutex.
+type priorityQueue struct {
+	mu sync.Mutex
+	// requested is the highest priority requested for a key since it was last
+	// pushed or touched.
+	requested map[any]Priority
+	// queues holds the queued keys of each priority in FIFO order.
+	queues [numPriorities][]any
+	// queued is the priority of each queued key.
+	queued map[any]Priority
+	// popped is the priority of each key being processed.
+	popped map[any]Priority
+}
+
+func newPriorityQueue() *priorityQueue {
+	return &priorityQueue{
+		requested: map[any]Priority{},
+		queued:    map[any]Priority{},
+		popped:    map[any]Priority{},
+	}
+}
+
+// request records that key is about to be added to the workqueue with the
+// given priority. The priority applies when the workqueue pushes the key, or
+// touches it because it is already queued.
+func (q *priorityQueue) request(key any, p Priority) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if current, ok := q.requested[key]; !ok || p > current {
+		q.requested[key] = p
+	}
+}
+
+// retryPriority returns the priority to retry a key that failed to sync
+// with: its priority when it was popped, but at least PriorityNew.
+func (q *priorityQueue) retryPriority(key any) Priority {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	p := q.popped[key]
+	delete(q.popped, key)
+	return max(p, PriorityNew)
+}
+
+// forget drops what is known about a key that was processed successfully.
+func (q *priorityQueue) forget(key any) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.popped, key)
+}
+
+// Touch implements workqueue.Queue. It moves a queued key up if a higher
+// priority was requested for it.
+func (q *priorityQueue) Touch(key any) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	p, ok := q.requested[key]
+	if !ok {
+		return
+	}
+	delete(q.requested, key)
+	current, ok := q.queued[key]
+	if !ok || p <= current {
+		return
+	}
+	q.queues[current] = slices.DeleteFunc(q.queues[current], func(k any) bool { return k == key })
+	q.queues[p] = append(q.queues[p], key)
+	q.queued[key] = p
+}
+
+// Push implements workqueue.Queue.
+func (q *priorityQueue) Push(key any) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	p := q.requested[key]
+	delete(q.requested, key)
+	q.queues[p] = append(q.queues[p], key)
+	q.queued[key] = p
+}
+
+// Len implements workqueue.Queue.
+func (q *priorityQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queued)
+}
+
+// Pop implements workqueue.Queue. It returns the oldest key of the highest
+// priority. The workqueue only calls Pop on a non-empty queue.
+func (q *priorityQueue) Pop() any {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for p := numPriorities - 1; p >= 0; p-- {
+		if len(q.queues[p]) == 0 {
+			continue
+		}
+		key := q.queues[p][0]
+		q.queues[p][0] = nil
+		q.queues[p] = q.queues[p][1:]
+		delete(q.queued, key)
+		q.popped[key] = Priority(p)
+		return key
+	}
+	return nil
+}
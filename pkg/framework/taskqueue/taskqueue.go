@@ -3,7 +3,10 @@ package taskqueue
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -34,6 +37,20 @@ type TaskQueue interface {
 	ShuttingDown() bool
 }
 
+// PriorityTaskQueue is a TaskQueue that can also enqueue keys with a priority
+// or after a delay. It is separate from TaskQueue so that existing
+// implementations of TaskQueue keep satisfying it; callers that hold a
+// TaskQueue can type-assert it to PriorityTaskQueue.
+type PriorityTaskQueue interface {
+	TaskQueue
+	// EnqueueWithPriority adds the key of obj to the work queue with the given
+	// priority. The priority is ignored unless the queue was created with
+	// WithPriorities.
+	EnqueueWithPriority(obj any, priority Priority)
+	// EnqueueAfter adds the key of obj to the work queue once the given delay has passed.
+	EnqueueAfter(obj any, delay time.Duration)
+}
+
 // PeriodicTaskQueueWithMultipleWorkers invokes the given sync function for every work item
 // inserted, while running n parallel worker routines. If the sync() function results in an error, the item is put on
 // the work queue after a rate-limit.
@@ -50,6 +67,16 @@ type PeriodicTaskQueueWithMultipleWorkers struct {
 	workerDone []chan struct{}
 	// numWorkers indicates the number of worker routines processing the queue.
 	numWorkers int
+	// started is set once Run has spawned the workers.
+	started atomic.Bool
+	// maxRequeues is how many times a failing key is retried before it is
+	// dropped. Zero retries forever.
+	maxRequeues int
+	// priorities orders the keys of the queue by priority. It is nil unless
+	// the queue was created with WithPriorities.
+	priorities *priorityQueue
+	// logger is the logger the workers derive their loggers from.
+	logger klog.Logger
 }
 
 // Len returns the length of the queue.
@@ -69,30 +96,52 @@ func (t *PeriodicTaskQueueWithMultipleWorkers) NumRequeues(obj any) int {
 }
 
 // runInternal invokes the worker routine to pick up and process an item from the queue. This blocks until ShutDown is called.
+// The sync function gets a logger with the worker ID through its context.
 func (t *PeriodicTaskQueueWithMultipleWorkers) runInternal(workerID int) {
-	ctx := context.Background()
+	logger := t.logger.WithValues("resource", t.resource, "workerID", workerID)
+	ctx := klog.NewContext(context.Background(), logger)
 	for {
 		key, quit := t.queue.Get()
 		if quit {
 			close(t.workerDone[workerID])
 			return
 		}
-		klog.V(4).InfoS("Syncing", "workerID", workerID, "key", key, "resource", t.resource)
+		logger.V(4).Info("Syncing", "key", key)
 		if err := t.sync(ctx, key.(string)); err != nil {
-			klog.Errorf("Requeuing due to error: %v, workerID: %v, key: %v, resource: %v", err, workerID, key, t.resource)
-			t.queue.AddRateLimited(key)
+			if t.maxRequeues > 0 && t.queue.NumRequeues(key) >= t.maxRequeues {
+				logger.Error(err, "Dropping key after too many retries", "key", key, "maxRequeues", t.maxRequeues)
+				t.forget(key)
+			} else {
+				logger.Error(err, "Requeuing after sync error", "key", key)
+				if t.priorities != nil {
+					t.priorities.request(key, t.priorities.retryPriority(key))
+				}
+				t.queue.AddRateLimited(key)
+			}
 		} else {
-			klog.V(4).InfoS("Finished syncing", "workerID", workerID, "key", key)
-			t.queue.Forget(key)
+			logger.V(4).Info("Finished syncing", "key", key)
+			t.forget(key)
 		}
 		t.queue.Done(key)
 	}
 }
 
+// forget stops tracking the retries and the priority of key.
+func (t *PeriodicTaskQueueWithMultipleWorkers) forget(key any) {
+	t.queue.Forget(key)
+	if t.priorities != nil {
+		t.priorities.forget(key)
+	}
+}
+
 // Run spawns off n parallel worker routines and returns immediately.
 func (t *PeriodicTaskQueueWithMultipleWorkers) Run() {
+	if !t.started.CompareAndSwap(false, true) {
+		klog.Errorf("Task queue for resource %v is already running", t.resource)
+		return
+	}
 	for worker := 0; worker < t.numWorkers; worker++ {
-		klog.InfoS("Spawning off worker for taskQueue", "workerID", worker, "resource", t.resource)
+		t.logger.Info("Spawning off worker for taskQueue", "workerID", worker, "resource", t.resource)
 		go t.runInternal(worker)
 	}
 }
@@ -110,10 +159,39 @@ func (t *PeriodicTaskQueueWithMultipleWorkers) Enqueue(objs ...any) {
 	}
 }
 
+// EnqueueWithPriority adds the key of obj to the work queue with the given
+// priority. A key that is already queued moves up if the priority is higher.
+func (t *PeriodicTaskQueueWithMultipleWorkers) EnqueueWithPriority(obj any, priority Priority) {
+	key, err := t.keyFunc(obj)
+	if err != nil {
+		klog.Errorf("Couldn't get key for object: %v, objectType: %T, error: %v", fmt.Sprintf("%+v", obj), obj, err)
+		return
+	}
+	klog.V(4).InfoS("Enqueue key with priority", "key", key, "priority", priority, "resource", t.resource)
+	if t.priorities != nil {
+		t.priorities.request(key, priority)
+	}
+	t.queue.Add(key)
+}
+
+// EnqueueAfter adds the key of obj to the work queue once the given delay has passed.
+func (t *PeriodicTaskQueueWithMultipleWorkers) EnqueueAfter(obj any, delay time.Duration) {
+	key, err := t.keyFunc(obj)
+	if err != nil {
+		klog.Errorf("Couldn't get key for object: %v, objectType: %T, error: %v", fmt.Sprintf("%+v", obj), obj, err)
+		return
+	}
+	klog.V(4).InfoS("Enqueue key after delay", "key", key, "delay", delay, "resource", t.resource)
+	t.queue.AddAfter(key, delay)
+}
+
 // Shutdown shuts down the work queue and waits for all the workers to ACK
 func (t *PeriodicTaskQueueWithMultipleWorkers) Shutdown() {
 	klog.V(2).InfoS("Shutting down task queue for resource", "resource", t.resource)
 	t.queue.ShutDown()
+	if !t.started.Load() {
+		return
+	}
 	// wait for all workers to shutdown.
 	for _, workerDone := range t.workerDone {
 		<-workerDone
@@ -125,25 +203,46 @@ func (t *PeriodicTaskQueueWithMultipleWorkers) ShuttingDown() bool {
 	return t.queue.ShuttingDown()
 }
 
-// NewPeriodicTaskQueueWithMultipleWorkers creates a new task queue with the default rate limiter and the given number of worker goroutines.
-func NewPeriodicTaskQueueWithMultipleWorkers(name, resource string, numWorkers int, syncFn func(context.Context, string) error) *PeriodicTaskQueueWithMultipleWorkers {
+// NewPeriodicTaskQueueWithMultipleWorkers creates a new task queue with the given number of worker goroutines.
+// By default, it uses the same rate limiter as workqueue.DefaultControllerRateLimiter and a FIFO queue; opts
+// tune the rate limiter, the requeue limit, the clock, the key function, the logger and the order of keys. It returns nil if numWorkers or any of opts is invalid.
+func NewPeriodicTaskQueueWithMultipleWorkers(name, resource string, numWorkers int, syncFn func(context.Context, string) error, opts ...Option) *PeriodicTaskQueueWithMultipleWorkers {
 	if numWorkers <= 0 {
 		klog.Errorf("Invalid worker count: %v", numWorkers)
 		return nil
 	}
-	rl := workqueue.DefaultControllerRateLimiter()
-	var queue workqueue.RateLimitingInterface
-	if name == "" {
-		queue = workqueue.NewRateLimitingQueue(rl)
-	} else {
-		queue = workqueue.NewNamedRateLimitingQueue(rl, name)
+	o := newOptions(opts...)
+	if err := errors.Join(o.errs...); err != nil {
+		klog.Errorf("Invalid task queue options: %v", err)
+		return nil
+	}
+	config := workqueue.RateLimitingQueueConfig{
+		Name:  name,
+		Clock: o.clock,
+	}
+	var priorities *priorityQueue
+	if o.priorities {
+		priorities = newPriorityQueue()
+		config.DelayingQueue = workqueue.NewDelayingQueueWithConfig(workqueue.DelayingQueueConfig{
+			Name:  name,
+			Clock: o.clock,
+			Queue: workqueue.NewWithConfig(workqueue.QueueConfig{
+				Name:  name,
+				Clock: o.clock,
+				Queue: priorities,
+			}),
+		})
 	}
+	queue := workqueue.NewRateLimitingQueueWithConfig(o.buildRateLimiter(), config)
 	taskQueue := &PeriodicTaskQueueWithMultipleWorkers{
-		resource:   resource,
-		keyFunc:    KeyFunc,
-		queue:      queue,
-		sync:       syncFn,
-		numWorkers: numWorkers,
+		resource:    resource,
+		keyFunc:     o.keyFunc,
+		queue:       queue,
+		sync:        syncFn,
+		numWorkers:  numWorkers,
+		maxRequeues: o.maxRequeues,
+		priorities:  priorities,
+		logger:      o.logger,
 	}
 	for worker := 0; worker < numWorkers; worker++ {
 		taskQueue.workerDone = append(taskQueue.workerDone, make(chan struct{}))
@@ -3,11 +3,15 @@ package taskqueue
 import (
 	"context"
 	"errors"
+	"slices"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-logr/logr"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	testingclock "k8s.io/utils/clock/testing"
 )
 
 func TestPeriodicQueueWithMultipleWorkers(t *testing.T) {
@@ -248,3 +252,303 @@ func TestEnqueueError(t *testing.T) {
 		t.Errorf("Expected queue length 0 after Enqueue error, got %d", tq.Len())
 	}
 }
+
+// TestWithKeyFunc verifies that keys are computed with the key function passed to the queue.
+func TestWithKeyFunc(t *testing.T) {
+	t.Parallel()
+	synced := make(chan string, 1)
+	syncFn := func(_ context.Context, key string) error {
+		synced <- key
+		return nil
+	}
+	keyFunc := func(obj any) (string, error) {
+		return "custom/" + obj.(string), nil
+	}
+	tq := NewPeriodicTaskQueueWithMultipleWorkers("key-queue", "test", 1, syncFn, WithKeyFunc(keyFunc))
+	if tq == nil {
+		t.Fatal("Failed to create task queue")
+	}
+	tq.Run()
+	defer tq.Shutdown()
+
+	tq.Enqueue("item")
+
+	select {
+	case key := <-synced:
+		if key != "custom/item" {
+			t.Errorf("Synced key %q, want %q", key, "custom/item")
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timed out waiting for item to be processed")
+	}
+}
+
+// valuesSink is a logr.LogSink that only records the values added to it.
+type valuesSink struct {
+	values []any
+}
+
+func (s *valuesSink) Init(logr.RuntimeInfo)        {}
+func (s *valuesSink) Enabled(int) bool             { return false }
+func (s *valuesSink) Info(int, string, ...any)     {}
+func (s *valuesSink) Error(error, string, ...any)  {}
+func (s *valuesSink) WithName(string) logr.LogSink { return s }
+func (s *valuesSink) WithValues(kvs ...any) logr.LogSink {
+	return &valuesSink{values: append(slices.Clone(s.values), kvs...)}
+}
+
+// TestWithLogger verifies that the sync function gets the logger of the queue
+// with the worker ID through its context.
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+	values := make(chan []any, 1)
+	syncFn := func(ctx context.Context, _ string) error {
+		values <- klog.FromContext(ctx).GetSink().(*valuesSink).values
+		return nil
+	}
+	logger := logr.New(&valuesSink{values: []any{"component", "test"}})
+	tq := NewPeriodicTaskQueueWithMultipleWorkers("logger-queue", "test", 1, syncFn, WithLogger(logger))
+	if tq == nil {
+		t.Fatal("Failed to create task queue")
+	}
+	tq.Run()
+	defer tq.Shutdown()
+
+	tq.Enqueue(cache.ExplicitKey("item"))
+
+	select {
+	case got := <-values:
+		want := []any{"component", "test", "resource", "test", "workerID", 0}
+		if !slices.Equal(got, want) {
+			t.Errorf("Sync logger has values %v, want %v", got, want)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timed out waiting for item to be processed")
+	}
+}
+
+// TestEnqueueAfter verifies that EnqueueAfter delays processing of the key
+// until the delay has passed.
+func TestEnqueueAfter(t *testing.T) {
+	t.Parallel()
+	syncedAt := make(chan time.Time, 1)
+	syncFn := func(_ context.Context, _ string) error {
+		syncedAt <- time.Now()
+		return nil
+	}
+	tq := NewPeriodicTaskQueueWithMultipleWorkers("delay-queue", "test", 1, syncFn)
+	if tq == nil {
+		t.Fatal("Failed to create task queue")
+	}
+	tq.Run()
+	defer tq.Shutdown()
+
+	delay := 100 * time.Millisecond
+	enqueuedAt := time.Now()
+	tq.EnqueueAfter(cache.ExplicitKey("delayed"), delay)
+
+	select {
+	case at := <-syncedAt:
+		if elapsed := at.Sub(enqueuedAt); elapsed < delay {
+			t.Errorf("Key was synced after %v, expected at least %v", elapsed, delay)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timed out waiting for delayed item to be processed")
+	}
+}
+
+// TestShutdownWithoutRun verifies that Shutdown returns for a queue whose workers
+// were never started.
+func TestShutdownWithoutRun(t *testing.T) {
+	t.Parallel()
+	syncFn := func(_ context.Context, _ string) error { return nil }
+	tq := NewPeriodicTaskQueueWithMultipleWorkers("never-run-queue", "test", 1, syncFn)
+	if tq == nil {
+		t.Fatal("Failed to create task queue")
+	}
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		tq.Shutdown()
+		close(shutdownDone)
+	}()
+
+	select {
+	case <-shutdownDone:
+		// Success
+	case <-time.After(1 * time.Second):
+		t.Fatal("Shutdown did not return for a queue that was never run")
+	}
+}
+
+// TestMaxRequeuesDropsKey verifies that a key that keeps failing is dropped
+// after the configured number of retries.
+func TestMaxRequeuesDropsKey(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	calls := 0
+	syncFn := func(_ context.Context, _ string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return errors.New("injected error")
+	}
+	tq := NewPeriodicTaskQueueWithMultipleWorkers("max-requeues-queue", "test", 1, syncFn,
+		WithItemBackoff(time.Millisecond, time.Millisecond),
+		WithMaxRequeues(2),
+	)
+	if tq == nil {
+		t.Fatal("Failed to create task queue")
+	}
+	tq.Run()
+	defer tq.Shutdown()
+
+	tq.Enqueue(cache.ExplicitKey("failing"))
+
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 3 {
+		t.Errorf("Expected 3 sync attempts (1 + 2 retries), got %d", calls)
+	}
+	if n := tq.NumRequeues(cache.ExplicitKey("failing")); n != 0 {
+		t.Errorf("Expected dropped key to be forgotten, got %d requeues", n)
+	}
+}
+
+// TestInvalidOptions verifies that invalid options are rejected.
+func TestInvalidOptions(t *testing.T) {
+	t.Parallel()
+	syncFn := func(_ context.Context, _ string) error { return nil }
+	testCases := []struct {
+		desc string
+		opt  Option
+	}{
+		{"nil rate limiter", WithRateLimiter(nil)},
+		{"zero base delay", WithItemBackoff(0, time.Second)},
+		{"max delay below base delay", WithItemBackoff(time.Second, time.Millisecond)},
+		{"zero qps", WithOverallRateLimit(0, 10)},
+		{"zero burst", WithOverallRateLimit(10, 0)},
+		{"negative max requeues", WithMaxRequeues(-1)},
+		{"nil clock", WithClock(nil)},
+		{"nil key function", WithKeyFunc(nil)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := ValidateOptions(tc.opt); err == nil {
+				t.Error("Expected ValidateOptions to return an error")
+			}
+			if tq := NewPeriodicTaskQueueWithMultipleWorkers("invalid", "test", 1, syncFn, tc.opt); tq != nil {
+				t.Error("Expected nil queue for invalid options")
+			}
+		})
+	}
+}
+
+// TestSetsOwnedOptions verifies that SetsKeyFunc and SetsClock only report the
+// options setting the key function and the clock.
+func TestSetsOwnedOptions(t *testing.T) {
+	t.Parallel()
+	other := []Option{WithPriorities(), WithMaxRequeues(3)}
+	if SetsKeyFunc(other...) || SetsClock(other...) {
+		t.Error("Did not expect other options to set the key function or the clock")
+	}
+	if !SetsKeyFunc(append(other, WithKeyFunc(KeyFunc))...) {
+		t.Error("Expected WithKeyFunc to set the key function")
+	}
+	if !SetsClock(append(other, WithClock(testingclock.NewFakeClock(time.Now())))...) {
+		t.Error("Expected WithClock to set the clock")
+	}
+}
+
+// TestEnqueueAfterWithFakeClock verifies that delayed keys follow the injected clock.
+func TestEnqueueAfterWithFakeClock(t *testing.T) {
+	t.Parallel()
+	synced := make(chan string, 1)
+	syncFn := func(_ context.Context, key string) error {
+		synced <- key
+		return nil
+	}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	tq := NewPeriodicTaskQueueWithMultipleWorkers("fake-clock-queue", "test", 1, syncFn, WithClock(fakeClock))
+	if tq == nil {
+		t.Fatal("Failed to create task queue")
+	}
+	tq.Run()
+	defer tq.Shutdown()
+
+	tq.EnqueueAfter(cache.ExplicitKey("delayed"), time.Hour)
+	select {
+	case <-synced:
+		t.Fatal("Key was synced before the fake clock advanced")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	fakeClock.Step(time.Hour)
+	select {
+	case key := <-synced:
+		if key != "delayed" {
+			t.Errorf("Expected key 'delayed', got %q", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for delayed item after the fake clock advanced")
+	}
+}
+
+// TestPriorities verifies that a queue created with WithPriorities syncs keys
+// by priority, in FIFO order within a priority, and moves queued keys up when
+// they are enqueued again with a higher priority.
+func TestPriorities(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	syncFn := func(_ context.Context, key string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, key)
+		return nil
+	}
+	tq := NewPeriodicTaskQueueWithMultipleWorkers("priorities", "test", 1, syncFn, WithPriorities())
+
+	tq.Enqueue(cache.ExplicitKey("routine-1"), cache.ExplicitKey("routine-2"))
+	tq.EnqueueWithPriority(cache.ExplicitKey("new"), PriorityNew)
+	tq.EnqueueWithPriority(cache.ExplicitKey("terminating"), PriorityTerminating)
+	tq.EnqueueWithPriority(cache.ExplicitKey("routine-2"), PriorityTerminating)
+	// Enqueueing a key again with a lower priority does not move it down.
+	tq.Enqueue(cache.ExplicitKey("new"))
+	if got := tq.Len(); got != 4 {
+		t.Errorf("Len() = %d, want 4", got)
+	}
+
+	tq.Run()
+	tq.Shutdown()
+
+	want := []string{"terminating", "routine-2", "new", "routine-1"}
+	if !slices.Equal(order, want) {
+		t.Errorf("Synced keys in order %v, want %v", order, want)
+	}
+}
+
+// TestPriorityQueueRetryPriority verifies that keys that failed to sync are
+// retried with at least PriorityNew.
+func TestPriorityQueueRetryPriority(t *testing.T) {
+	q := newPriorityQueue()
+	q.Push("routine")
+	q.request("terminating", PriorityTerminating)
+	q.Push("terminating")
+
+	if key := q.Pop(); key != "terminating" {
+		t.Fatalf("Pop() = %v, want terminating", key)
+	}
+	if key := q.Pop(); key != "routine" {
+		t.Fatalf("Pop() = %v, want routine", key)
+	}
+	if p := q.retryPriority("routine"); p != PriorityNew {
+		t.Errorf("retryPriority(routine) = %v, want %v", p, PriorityNew)
+	}
+	if p := q.retryPriority("terminating"); p != PriorityTerminating {
+		t.Errorf("retryPriority(terminating) = %v, want %v", p, PriorityTerminating)
+	}
+	if len(q.popped) != 0 {
+		t.Errorf("Expected no popped keys to be tracked, got %v", q.popped)
+	}
+}
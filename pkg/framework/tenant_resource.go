@@ -0,0 +1,182 @@
+package framework
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// KeyFunc returns the key of a tenant object. Keys identify tenants in the
+// queue and in the ControllerMap, and are used to look objects up in the
+// indexer of the informer passed to New, so a KeyFunc must return the keys of
+// that indexer.
+type KeyFunc func(obj *unstructured.Unstructured) string
+
+// TenantUIDFunc returns the UID of the tenant defined by obj. The tenant UID is
+// passed to the controllers of the tenant through the context.
+type TenantUIDFunc func(obj *unstructured.Unstructured) (string, error)
+
+// tenantResource describes the resource whose objects define tenants.
+type tenantResource struct {
+	gvr schema.GroupVersionResource
+	gvk schema.GroupVersionKind
+	// namespaced is set if the objects of the resource live in namespaces.
+	namespaced    bool
+	keyFunc       KeyFunc
+	tenantUIDFunc TenantUIDFunc
+}
+
+// defaultTenantResource returns the cloud.gke.io/v1 ProviderConfig resource,
+// whose objects are keyed by name and whose name is the tenant UID.
+func defaultTenantResource() tenantResource {
+	return tenantResource{
+		gvr:     providerConfigGVR,
+		gvk:     providerConfigGVK,
+		keyFunc: metaNamespaceKeyFunc,
+		tenantUIDFunc: func(obj *unstructured.Unstructured) (string, error) {
+			return obj.GetName(), nil
+		},
+	}
+}
+
+// metaNamespaceKeyFunc keys objects like the default indexer: namespaced
+// objects by namespace/name and cluster-scoped objects by name.
+func metaNamespaceKeyFunc(obj *unstructured.Unstructured) string {
+	return cache.MetaObjectToName(obj).String()
+}
+
+// key returns the key of obj.
+func (r tenantResource) key(obj *unstructured.Unstructured) string {
+	return r.keyFunc(obj)
+}
+
+// queueKey returns the key of an object passed to the queue: a tenant object,
+// a tombstone of one, or a cache.ExplicitKey.
+func (r tenantResource) queueKey(obj any) (string, error) {
+	switch o := obj.(type) {
+	case cache.ExplicitKey:
+		return string(o), nil
+	case cache.DeletedFinalStateUnknown:
+		return o.Key, nil
+	case *unstructured.Unstructured:
+		return r.keyFunc(o), nil
+	default:
+		return "", fmt.Errorf("expected *unstructured.Unstructured but got %T", obj)
+	}
+}
+
+// tenantUID returns the UID of the tenant defined by obj.
+func (r tenantResource) tenantUID(obj *unstructured.Unstructured) (string, error) {
+	uid, err := r.tenantUIDFunc(obj)
+	if err != nil {
+		return "", err
+	}
+	if uid == "" {
+		return "", fmt.Errorf("empty tenant UID for %s %s", r.gvk.Kind, r.key(obj))
+	}
+	return uid, nil
+}
+
+// checkKind returns an error unless obj is of the tenant kind.
+func (r tenantResource) checkKind(obj *unstructured.Unstructured) error {
+	if obj.GroupVersionKind() != r.gvk {
+		return fmt.Errorf("expected object of kind %s, but got %s", r.gvk, obj.GroupVersionKind())
+	}
+	return nil
+}
+
+// resource returns the client for the tenant resource of obj, scoped to the
+// namespace of obj if the resource is namespaced.
+func (r tenantResource) resource(client dynamic.Interface, obj *unstructured.Unstructured) dynamic.ResourceInterface {
+	if r.namespaced {
+		return client.Resource(r.gvr).Namespace(obj.GetNamespace())
+	}
+	return client.Resource(r.gvr)
+}
+
+// WithTenantResource makes the framework manage tenants defined by objects of
+// the given cluster-scoped resource and kind, e.g. tenancy.gke.io Tenants,
+// instead of cloud.gke.io/v1 ProviderConfigs. The informer passed to New must
+// watch the same resource.
+func WithTenantResource(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind) Option {
+	return func(o *options) {
+		if gvr.Resource == "" || gvk.Kind == "" {
+			o.errs = append(o.errs, fmt.Errorf("tenant resource requires a resource and a kind, got %s and %s", gvr, gvk))
+			return
+		}
+		o.tenants.gvr = gvr
+		o.tenants.gvk = gvk
+		o.tenants.namespaced = false
+	}
+}
+
+// WithNamespacedTenantResource makes the framework manage tenants defined by
+// objects of the given namespaced resource and kind. Tenants are keyed by
+// namespace/name, and the finalizer and status of each object are updated in
+// its namespace. Since names are only unique within a namespace, the tenant
+// UID should be read from a field with WithTenantUIDField or
+// WithTenantUIDFunc. The informer passed to New must watch the same resource.
+func WithNamespacedTenantResource(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind) Option {
+	return func(o *options) {
+		if gvr.Resource == "" || gvk.Kind == "" {
+			o.errs = append(o.errs, fmt.Errorf("tenant resource requires a resource and a kind, got %s and %s", gvr, gvk))
+			return
+		}
+		o.tenants.gvr = gvr
+		o.tenants.gvk = gvk
+		o.tenants.namespaced = true
+	}
+}
+
+// WithKeyFunc sets how tenant objects are keyed. It must return the keys of
+// the indexer of the informer passed to New. By default, namespaced objects
+// are keyed by namespace/name and cluster-scoped objects by name.
+func WithKeyFunc(keyFunc KeyFunc) Option {
+	return func(o *options) {
+		if keyFunc == nil {
+			o.errs = append(o.errs, errors.New("key function must not be nil"))
+			return
+		}
+		o.tenants.keyFunc = keyFunc
+	}
+}
+
+// WithTenantUIDFunc sets how the tenant UID is extracted from tenant objects.
+// By default, the name of the object is the tenant UID.
+func WithTenantUIDFunc(tenantUIDFunc TenantUIDFunc) Option {
+	return func(o *options) {
+		if tenantUIDFunc == nil {
+			o.errs = append(o.errs, errors.New("tenant UID function must not be nil"))
+			return
+		}
+		o.tenants.tenantUIDFunc = tenantUIDFunc
+	}
+}
+
+// WithTenantUIDField reads the tenant UID from the string field of tenant
+// objects at the given path, e.g. "spec", "tenantUID". Objects without the
+// field are not started. Terminating objects without the field are still
+// stopped, with the tenant UID recorded when their controllers were started.
+func WithTenantUIDField(fields ...string) Option {
+	return func(o *options) {
+		if len(fields) == 0 {
+			o.errs = append(o.errs, errors.New("tenant UID field must not be empty"))
+			return
+		}
+		o.tenants.tenantUIDFunc = func(obj *unstructured.Unstructured) (string, error) {
+			uid, found, err := unstructured.NestedString(obj.Object, fields...)
+			if err != nil {
+				return "", fmt.Errorf("failed to read tenant UID field %s: %w", strings.Join(fields, "."), err)
+			}
+			if !found {
+				return "", fmt.Errorf("tenant UID field %s not found", strings.Join(fields, "."))
+			}
+			return uid, nil
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+
+	mtcontext "github.com/GoogleCloudPlatform/gke-enterprise-mt/pkg/framework/mtcontext"
+)
+
+var (
+	testTenantGVK = schema.GroupVersionKind{Group: "tenancy.gke.io", Version: "v1alpha1", Kind: "Tenant"}
+	testTenantGVR = schema.GroupVersionResource{Group: "tenancy.gke.io", Version: "v1alpha1", Resource: "tenants"}
+)
+
+const testTenantUIDLabel = "tenancy.gke.io/tenant-uid"
+
+func createTestTenant(name, tenantUID string) *unstructured.Unstructured {
+	tenant := &unstructured.Unstructured{}
+	tenant.SetGroupVersionKind(testTenantGVK)
+	tenant.SetName(name)
+	if tenantUID != "" {
+		tenant.SetLabels(map[string]string{testTenantUIDLabel: tenantUID})
+	}
+	return tenant
+}
+
+func tenantUIDFromLabel(obj *unstructured.Unstructured) (string, error) {
+	return obj.GetLabels()[testTenantUIDLabel], nil
+}
+
+// TestCustomTenantResource verifies that the framework manages tenants defined
+// by a resource other than ProviderConfig, keyed and identified by the
+// configured functions.
+func TestCustomTenantResource(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	informer := &fakeInformer{Indexer: indexer, synced: true}
+	starter := newContextControllerStarter()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	ctrl := New(dynamicClient, informer, "test-finalizer", AdaptContextControllerStarter(starter), stopCh,
+		WithTenantResource(testTenantGVR, testTenantGVK),
+		WithTenantUIDFunc(tenantUIDFromLabel),
+	)
+	go ctrl.Run()
+
+	tenant := createTestTenant("tenant-a", "uid-a")
+	if _, err := dynamicClient.Resource(testTenantGVR).Create(ctx, tenant, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create Tenant: %v", err)
+	}
+	if err := indexer.Add(tenant); err != nil {
+		t.Fatalf("Failed to add Tenant to indexer: %v", err)
+	}
+	informer.handler.OnAdd(tenant, false)
+
+	var starterCtx context.Context
+	if err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		starterCtx, _ = starter.get("tenant-a")
+		return starterCtx != nil, nil
+	}); err != nil {
+		t.Fatal("Timed out waiting for the controllers of the Tenant to start")
+	}
+	if got, want := mtcontext.TenantUIDFromContext(starterCtx), "tenant-uid:uid-a"; got != want {
+		t.Errorf("TenantUIDFromContext() = %v, want %v", got, want)
+	}
+
+	latest, err := dynamicClient.Resource(testTenantGVR).Get(ctx, "tenant-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get Tenant: %v", err)
+	}
+	if !hasFinalizer(latest, "test-finalizer") {
+		t.Errorf("Expected finalizer on Tenant, got %v", latest.GetFinalizers())
+	}
+}
+
+// TestCustomTenantResourceRejectsOtherKinds verifies that the manager refuses
+// objects that are not of the configured tenant kind.
+func TestCustomTenantResourceRejectsOtherKinds(t *testing.T) {
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newMockControllerStarter()
+	manager := newManager(dynamicClient, "test-finalizer", starter, WithTenantResource(testTenantGVR, testTenantGVK))
+
+	if err := manager.StartControllersForProviderConfig(context.Background(), createTestProviderConfig("pc-1")); err == nil {
+		t.Error("Expected an error when starting controllers for a ProviderConfig")
+	}
+	if got := starter.getStartCallCount(); got != 0 {
+		t.Errorf("Expected no start calls, got %d", got)
+	}
+}
+
+// TestSyncMissingTenantUID verifies that objects without a tenant UID are not
+// started.
+func TestSyncMissingTenantUID(t *testing.T) {
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	fakeManager := newFakeProviderConfigControllersManager(dynamicClient, "test-finalizer")
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	ctrl := newController(fakeManager, &fakeInformer{Indexer: indexer, synced: true}, make(chan struct{}),
+		WithTenantResource(testTenantGVR, testTenantGVK),
+		WithTenantUIDFunc(tenantUIDFromLabel),
+	)
+
+	tenant := createTestTenant("tenant-b", "")
+	if err := indexer.Add(tenant); err != nil {
+		t.Fatalf("Failed to add Tenant to indexer: %v", err)
+	}
+	if err := ctrl.sync(context.Background(), "tenant-b"); err == nil {
+		t.Error("Expected sync to fail for a Tenant without a tenant UID")
+	}
+	if fakeManager.HasStarted("tenant-b") {
+		t.Error("Expected controllers not to be started")
+	}
+}
+
+// TestTenantResourceQueueKey verifies the keys of the objects passed to the queue.
+func TestTenantResourceQueueKey(t *testing.T) {
+	tenants := defaultTenantResource()
+	tenants.keyFunc = func(obj *unstructured.Unstructured) string {
+		return "custom/" + obj.GetName()
+	}
+	testCases := []struct {
+		desc    string
+		obj     any
+		want    string
+		wantErr bool
+	}{
+		{desc: "object", obj: createTestProviderConfig("pc-1"), want: "custom/pc-1"},
+		{desc: "explicit key", obj: cache.ExplicitKey("pc-2"), want: "pc-2"},
+		{desc: "tombstone", obj: cache.DeletedFinalStateUnknown{Key: "custom/pc-3"}, want: "custom/pc-3"},
+		{desc: "unexpected type", obj: "pc-4", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tenants.queueKey(tc.obj)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("queueKey() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("queueKey() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestTenantResourceOptions verifies that invalid tenant resource options are rejected.
+func TestTenantResourceOptions(t *testing.T) {
+	testCases := []struct {
+		desc string
+		opt  Option
+	}{
+		{"empty resource", WithTenantResource(schema.GroupVersionResource{}, testTenantGVK)},
+		{"empty kind", WithTenantResource(testTenantGVR, schema.GroupVersionKind{})},
+		{"nil key function", WithKeyFunc(nil)},
+		{"nil tenant UID function", WithTenantUIDFunc(nil)},
+		{"empty namespaced resource", WithNamespacedTenantResource(schema.GroupVersionResource{}, testTenantGVK)},
+		{"empty tenant UID field", WithTenantUIDField()},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			o := newOptions(tc.opt)
+			if err := o.validate(); err == nil {
+				t.Error("Expected validate to return an error")
+			}
+			if o.tenants.gvr != providerConfigGVR || o.tenants.keyFunc == nil || o.tenants.tenantUIDFunc == nil {
+				t.Error("Expected the default tenant resource to be kept")
+			}
+		})
+	}
+	failing := WithTenantUIDFunc(func(*unstructured.Unstructured) (string, error) {
+		return "", errors.New("no tenant UID")
+	})
+	o := newOptions(failing)
+	if _, err := o.tenants.tenantUID(createTestProviderConfig("pc-1")); err == nil {
+		t.Error("Expected tenantUID to return the error of the tenant UID function")
+	}
+}
+
+// TestSyncTerminatingTenantWithoutTenantUID verifies that a terminating tenant
+// whose tenant UID field is missing still stops its controllers and releases
+// its finalizer, using the tenant UID recorded when they were started.
+func TestSyncTerminatingTenantWithoutTenantUID(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	cleanedUp := map[string]any{}
+	ctrl := New(dynamicClient, &fakeInformer{Indexer: indexer, synced: true}, "test-finalizer", newMockControllerStarter(), make(chan struct{}),
+		WithNamespacedTenantResource(testTenantGVR, testTenantGVK),
+		WithTenantUIDField("spec", "tenantUID"),
+		WithCleanupHook(func(ctx context.Context, key string) error {
+			cleanedUp[key] = mtcontext.TenantUIDFromContext(ctx)
+			return nil
+		}),
+	)
+	tenants := dynamicClient.Resource(testTenantGVR).Namespace("ns-a")
+
+	started := createTestNamespacedTenant("ns-a", "tenant-started", "uid-started")
+	if _, err := tenants.Create(ctx, started, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create Tenant: %v", err)
+	}
+	if err := indexer.Add(started); err != nil {
+		t.Fatalf("Failed to add Tenant to indexer: %v", err)
+	}
+	if err := ctrl.sync(ctx, "ns-a/tenant-started"); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	latest, err := tenants.Get(ctx, "tenant-started", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get Tenant: %v", err)
+	}
+	unstructured.RemoveNestedField(latest.Object, "spec", "tenantUID")
+	latest.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	if _, err := tenants.Update(ctx, latest, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update Tenant: %v", err)
+	}
+	if err := indexer.Update(latest); err != nil {
+		t.Fatalf("Failed to update Tenant in indexer: %v", err)
+	}
+
+	// A terminating tenant that was never started and has no tenant UID field.
+	neverStarted := createTestNamespacedTenant("ns-a", "tenant-never-started", "")
+	neverStarted.SetFinalizers([]string{"test-finalizer"})
+	neverStarted.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	if _, err := tenants.Create(ctx, neverStarted, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create Tenant: %v", err)
+	}
+	if err := indexer.Add(neverStarted); err != nil {
+		t.Fatalf("Failed to add Tenant to indexer: %v", err)
+	}
+
+	for _, name := range []string{"tenant-started", "tenant-never-started"} {
+		if err := ctrl.sync(ctx, "ns-a/"+name); err != nil {
+			t.Fatalf("sync of terminating %s failed: %v", name, err)
+		}
+		latest, err := tenants.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get Tenant: %v", err)
+		}
+		if hasFinalizer(latest, "test-finalizer") {
+			t.Errorf("Expected the finalizer of %s to be removed", name)
+		}
+	}
+	if got, want := cleanedUp["ns-a/tenant-started"], "tenant-uid:uid-started"; got != want {
+		t.Errorf("Cleanup hook of the started tenant ran with tenant UID %v, want %v", got, want)
+	}
+	if got, ok := cleanedUp["ns-a/tenant-never-started"]; !ok || got != nil {
+		t.Errorf("Expected the cleanup hook of the never started tenant to run without tenant UID, got %v (ran: %v)", got, ok)
+	}
+}
+
+func createTestNamespacedTenant(namespace, name, tenantUID string) *unstructured.Unstructured {
+	tenant := createTestTenant(name, "")
+	tenant.SetNamespace(namespace)
+	if tenantUID != "" {
+		if err := unstructured.SetNestedField(tenant.Object, tenantUID, "spec", "tenantUID"); err != nil {
+			panic(err)
+		}
+	}
+	return tenant
+}
+
+// TestNamespacedTenantResource verifies that tenants defined by namespaced
+// objects are keyed by namespace/name, that their finalizers are managed in
+// their namespace and that their tenant UID is read from the configured field.
+func TestNamespacedTenantResource(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	starter := newMockControllerStarter()
+	manager := newManager(dynamicClient, "test-finalizer", starter,
+		WithNamespacedTenantResource(testTenantGVR, testTenantGVK),
+		WithTenantUIDField("spec", "tenantUID"),
+	)
+
+	// Tenants with the same name in different namespaces are distinct.
+	tenants := []*unstructured.Unstructured{
+		createTestNamespacedTenant("ns-a", "tenant", "uid-a"),
+		createTestNamespacedTenant("ns-b", "tenant", "uid-b"),
+	}
+	for _, tenant := range tenants {
+		if _, err := dynamicClient.Resource(testTenantGVR).Namespace(tenant.GetNamespace()).Create(ctx, tenant, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Failed to create Tenant: %v", err)
+		}
+		if err := manager.StartControllersForProviderConfig(ctx, tenant); err != nil {
+			t.Fatalf("Start failed for %s/%s: %v", tenant.GetNamespace(), tenant.GetName(), err)
+		}
+	}
+
+	keys := manager.controllers.Keys()
+	slices.Sort(keys)
+	if want := []string{"ns-a/tenant", "ns-b/tenant"}; !slices.Equal(keys, want) {
+		t.Errorf("ControllerMap keys = %v, want %v", keys, want)
+	}
+	for key, want := range map[string]string{"ns-a/tenant": "uid-a", "ns-b/tenant": "uid-b"} {
+		cs, ok := manager.controllers.Get(key)
+		if !ok {
+			t.Fatalf("Expected a ControllerSet for %s", key)
+		}
+		if cs.getTenantUID() != want {
+			t.Errorf("Tenant UID of %s = %q, want %q", key, cs.getTenantUID(), want)
+		}
+	}
+	if got := starter.getStartCallCount(); got != 2 {
+		t.Errorf("Expected 2 start calls, got %d", got)
+	}
+
+	for _, ns := range []string{"ns-a", "ns-b"} {
+		latest, err := dynamicClient.Resource(testTenantGVR).Namespace(ns).Get(ctx, "tenant", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get Tenant in %s: %v", ns, err)
+		}
+		if !hasFinalizer(latest, "test-finalizer") {
+			t.Errorf("Expected finalizer on Tenant in %s, got %v", ns, latest.GetFinalizers())
+		}
+	}
+
+	// Stopping one tenant leaves the tenant of the same name in the other namespace running.
+	latest, err := dynamicClient.Resource(testTenantGVR).Namespace("ns-a").Get(ctx, "tenant", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get Tenant: %v", err)
+	}
+	latest.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	if err := manager.StopControllersForProviderConfig(ctx, latest); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if _, ok := manager.controllers.Get("ns-a/tenant"); ok {
+		t.Error("Expected the controllers of ns-a/tenant to be removed")
+	}
+	if _, ok := manager.controllers.Get("ns-b/tenant"); !ok {
+		t.Error("Expected the controllers of ns-b/tenant to keep running")
+	}
+	latest, err = dynamicClient.Resource(testTenantGVR).Namespace("ns-a").Get(ctx, "tenant", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get Tenant: %v", err)
+	}
+	if hasFinalizer(latest, "test-finalizer") {
+		t.Error("Expected the finalizer of ns-a/tenant to be removed")
+	}
+}
+
+// TestSyncNamespacedTenant verifies that the controller syncs namespaced
+// tenants by their namespace/name key and passes the tenant UID read from the
+// configured field.
+func TestSyncNamespacedTenant(t *testing.T) {
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), nil)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	starter := newContextControllerStarter()
+	ctrl := New(dynamicClient, &fakeInformer{Indexer: indexer, synced: true}, "test-finalizer", AdaptContextControllerStarter(starter), make(chan struct{}),
+		WithNamespacedTenantResource(testTenantGVR, testTenantGVK),
+		WithTenantUIDField("spec", "tenantUID"),
+	)
+
+	tenant := createTestNamespacedTenant("ns-a", "tenant-c", "uid-c")
+	if _, err := dynamicClient.Resource(testTenantGVR).Namespace("ns-a").Create(context.Background(), tenant, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create Tenant: %v", err)
+	}
+	if err := indexer.Add(tenant); err != nil {
+		t.Fatalf("Failed to add Tenant to indexer: %v", err)
+	}
+	if err := ctrl.sync(context.Background(), "ns-a/tenant-c"); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	starterCtx, _ := starter.get("tenant-c")
+	if starterCtx == nil {
+		t.Fatal("Expected the controllers of the Tenant to start")
+	}
+	if got, want := mtcontext.TenantUIDFromContext(starterCtx), "tenant-uid:uid-c"; got != want {
+		t.Errorf("TenantUIDFromContext() = %v, want %v", got, want)
+	}
+
+	// Tenants without the tenant UID field are not started.
+	missing := createTestNamespacedTenant("ns-a", "tenant-d", "")
+	if err := indexer.Add(missing); err != nil {
+		t.Fatalf("Failed to add Tenant to indexer: %v", err)
+	}
+	if err := ctrl.sync(context.Background(), "ns-a/tenant-d"); err == nil {
+		t.Error("Expected sync to fail for a Tenant without the tenant UID field")
+	}
+	if ctx, _ := starter.get("tenant-d"); ctx != nil {
+		t.Error("Expected the controllers of a Tenant without tenant UID not to start")
+	}
+}